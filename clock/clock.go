@@ -0,0 +1,191 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passing of time so that time-dependent code can be tested deterministically.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a new timer which sends the current time on its channel after the duration elapsed.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc waits for the duration to elapse and then calls f.
+	// The returned timer can be used to cancel the call, its channel is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event which can be stopped and reset, see time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer already fired or was stopped.
+	Stop() bool
+	// Reset changes the timer to fire after the duration. It returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+type realclock struct{}
+
+func (realclock) Now() time.Time {
+	return time.Now()
+}
+
+func (realclock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realclock) NewTimer(d time.Duration) Timer {
+	return realtimer{time.NewTimer(d)}
+}
+
+func (realclock) AfterFunc(d time.Duration, f func()) Timer {
+	return realtimer{time.AfterFunc(d, f)}
+}
+
+type realtimer struct {
+	*time.Timer
+}
+
+func (t realtimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// Real is the clock backed by the time package.
+var Real Clock = realclock{}
+
+// Fake is a clock which only moves when it is advanced manually.
+type Fake struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*faketimer
+}
+
+// NewFake creates a new fake clock set to the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+type faketimer struct {
+	clock    *Fake
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+}
+
+func (t *faketimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *faketimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.remove(t)
+}
+
+func (t *faketimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.clock.remove(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	return active
+}
+
+// remove removes the given timer and reports whether it was active. The clock must be locked.
+func (f *Fake) remove(t *faketimer) bool {
+	for i, other := range f.timers {
+		if other == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// After returns a channel which receives the time once the clock was advanced by at least the given duration.
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// NewTimer creates a new timer which fires once the clock was advanced by at least the given duration.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &faketimer{clock: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// AfterFunc calls f once the clock was advanced by at least the given duration.
+// f is called synchronously by Advance.
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &faketimer{clock: f, deadline: f.now.Add(d), f: fn}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by the given duration and fires all timers whose deadline passed
+// in the order of their deadlines. Functions registered via AfterFunc are called before Advance returns.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	end := f.now.Add(d)
+	for {
+		sort.SliceStable(f.timers, func(i, j int) bool {
+			return f.timers[i].deadline.Before(f.timers[j].deadline)
+		})
+		if len(f.timers) == 0 || f.timers[0].deadline.After(end) {
+			break
+		}
+		t := f.timers[0]
+		f.timers = f.timers[1:]
+		if t.deadline.After(f.now) {
+			f.now = t.deadline
+		}
+		if t.f != nil {
+			// the function might use the clock itself
+			f.mu.Unlock()
+			t.f()
+			f.mu.Lock()
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+	}
+	f.now = end
+	f.mu.Unlock()
+}
+
+// Waiters returns the amount of pending timers, which allows tests
+// to wait until a goroutine blocks on the clock before advancing it.
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.timers)
+}
+
+// BlockUntil blocks until at least n timers are pending.
+func (f *Fake) BlockUntil(n int) {
+	for f.Waiters() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
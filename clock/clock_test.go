@@ -0,0 +1,60 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	short := f.After(time.Second)
+	long := f.After(time.Minute)
+	if f.Waiters() != 2 {
+		t.Fatalf("waiters were %d, expected %d", f.Waiters(), 2)
+	}
+
+	f.Advance(time.Second)
+	select {
+	case now := <-short:
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("fired at %v, expected %v", now, start.Add(time.Second))
+		}
+	default:
+		t.Error("waiter didn't fire after its deadline passed")
+	}
+	select {
+	case <-long:
+		t.Error("waiter fired before its deadline")
+	default:
+	}
+	if f.Waiters() != 1 {
+		t.Errorf("waiters were %d, expected %d", f.Waiters(), 1)
+	}
+	if !f.Now().Equal(start.Add(time.Second)) {
+		t.Errorf("now was %v, expected %v", f.Now(), start.Add(time.Second))
+	}
+}
+
+func TestFakeTimers(t *testing.T) {
+	f := NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	calls := []string{}
+	f.AfterFunc(2*time.Second, func() { calls = append(calls, "second") })
+	first := f.AfterFunc(time.Second, func() { calls = append(calls, "first") })
+	stopped := f.AfterFunc(time.Second, func() { calls = append(calls, "stopped") })
+	if !stopped.Stop() {
+		t.Error("stopping a pending timer returned false")
+	}
+
+	f.Advance(3 * time.Second)
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Fatalf("calls were %v, expected [first second]", calls)
+	}
+	if first.Reset(time.Second) {
+		t.Error("resetting a fired timer returned true")
+	}
+	f.Advance(time.Second)
+	if len(calls) != 3 {
+		t.Errorf("calls were %v, expected the reset timer to fire again", calls)
+	}
+}
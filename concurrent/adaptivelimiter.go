@@ -0,0 +1,236 @@
+package concurrent
+
+import (
+	"container/list"
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// Outcome describes how an operation guarded by an adaptive limiter ended.
+type Outcome int
+
+const (
+	// Success marks an operation which completed normally.
+	Success Outcome = iota
+	// Dropped marks an operation which failed because the downstream is overloaded, e.g. a timeout.
+	Dropped
+	// Ignored marks an operation which should not influence the limit, e.g. a validation error.
+	Ignored
+)
+
+// Sample describes a single completed operation.
+type Sample struct {
+	// RTT is the time the operation took.
+	RTT time.Duration
+	// InFlight is the amount of operations which were in flight when the operation started.
+	InFlight int
+	// Dropped tells whether the operation was dropped.
+	Dropped bool
+}
+
+// LimitAlgorithm computes a new concurrency limit from the current limit and a sample.
+// Update is always called while the limiter is locked, so implementations don't need to synchronize their state.
+type LimitAlgorithm interface {
+	Update(limit int, sample Sample) int
+}
+
+// clamp returns the limit bounded by min and max.
+func clamp(limit, min, max int) int {
+	if limit < min {
+		return min
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// AIMD increases the limit by one after each successful operation and multiplies it
+// by the backoff ratio after each dropped operation or one which exceeded the timeout.
+type AIMD struct {
+	min, max int
+	backoff  float64
+	timeout  time.Duration
+}
+
+// NewAIMD creates a new additive-increase/multiplicative-decrease algorithm bounded by min and max.
+// The backoff ratio must be within (0,1). If timeout is > 0, operations taking longer count as dropped.
+func NewAIMD(min int, max int, backoff float64, timeout time.Duration) *AIMD {
+	return &AIMD{min, max, backoff, timeout}
+}
+
+// Update implements LimitAlgorithm.
+func (a *AIMD) Update(limit int, sample Sample) int {
+	if sample.Dropped || (a.timeout > 0 && sample.RTT > a.timeout) {
+		return clamp(int(float64(limit)*a.backoff), a.min, a.max)
+	}
+	// only grow if the limit is actually used
+	if sample.InFlight*2 >= limit {
+		return clamp(limit+1, a.min, a.max)
+	}
+	return limit
+}
+
+// Vegas estimates the queue at the downstream from the ratio of the lowest observed latency
+// to the current latency, increasing the limit while the queue is small and decreasing it once it grows.
+type Vegas struct {
+	min, max int
+	noLoad   time.Duration
+}
+
+// NewVegas creates a new Vegas-style algorithm bounded by min and max.
+func NewVegas(min int, max int) *Vegas {
+	return &Vegas{min: min, max: max}
+}
+
+// Update implements LimitAlgorithm.
+func (v *Vegas) Update(limit int, sample Sample) int {
+	if sample.RTT <= 0 {
+		return limit
+	}
+	if v.noLoad == 0 || sample.RTT < v.noLoad {
+		v.noLoad = sample.RTT
+	}
+	step := math.Max(1, math.Log10(float64(limit)))
+	if sample.Dropped {
+		return clamp(int(float64(limit)-step), v.min, v.max)
+	}
+	if sample.InFlight*2 < limit {
+		return limit
+	}
+	alpha, beta := 3*step, 6*step
+	queue := math.Ceil(float64(limit) * (1 - float64(v.noLoad)/float64(sample.RTT)))
+	switch {
+	case queue <= step:
+		return clamp(int(float64(limit)+beta), v.min, v.max)
+	case queue < alpha:
+		return clamp(int(float64(limit)+step), v.min, v.max)
+	case queue > beta:
+		return clamp(int(float64(limit)-step), v.min, v.max)
+	}
+	return limit
+}
+
+// AdaptiveLimiter limits the amount of concurrent operations.
+// The limit is adjusted by the given algorithm based on the latency and outcome of completed operations.
+type AdaptiveLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	inflight  int
+	algorithm LimitAlgorithm
+	waiters   *list.List
+	clock     clock.Clock
+}
+
+// Permit allows a single operation to run. It must be released once the operation is done.
+type Permit struct {
+	limiter  *AdaptiveLimiter
+	start    time.Time
+	inflight int
+	released bool
+}
+
+// NewAdaptiveLimiter creates a new adaptive limiter with the given initial limit.
+func NewAdaptiveLimiter(initial int, algorithm LimitAlgorithm) *AdaptiveLimiter {
+	return &AdaptiveLimiter{limit: initial, algorithm: algorithm, waiters: list.New(), clock: clock.Real}
+}
+
+// SetClock sets the clock used to measure the latency of operations.
+func (al *AdaptiveLimiter) SetClock(clk clock.Clock) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	al.clock = clk
+}
+
+// permit hands out a new permit. The limiter must be locked.
+func (al *AdaptiveLimiter) permit() *Permit {
+	al.inflight++
+	return &Permit{limiter: al, start: al.clock.Now(), inflight: al.inflight}
+}
+
+// grant hands out permits to waiters as long as the limit allows. The limiter must be locked.
+func (al *AdaptiveLimiter) grant() {
+	for al.inflight < al.limit && al.waiters.Len() > 0 {
+		w := al.waiters.Remove(al.waiters.Front()).(chan *Permit)
+		w <- al.permit()
+	}
+}
+
+// TryAcquire returns a permit if the limit allows another operation. It never blocks.
+func (al *AdaptiveLimiter) TryAcquire() (*Permit, bool) {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if al.inflight >= al.limit || al.waiters.Len() > 0 {
+		return nil, false
+	}
+	return al.permit(), true
+}
+
+// Acquire blocks until the limit allows another operation or the given context is done.
+// Waiting callers are served in order.
+func (al *AdaptiveLimiter) Acquire(ctx context.Context) (*Permit, error) {
+	al.mu.Lock()
+	if al.inflight < al.limit && al.waiters.Len() == 0 {
+		p := al.permit()
+		al.mu.Unlock()
+		return p, nil
+	}
+	w := make(chan *Permit, 1)
+	ele := al.waiters.PushBack(w)
+	al.mu.Unlock()
+
+	select {
+	case p := <-w:
+		return p, nil
+	case <-ctx.Done():
+		al.mu.Lock()
+		select {
+		case p := <-w:
+			// the permit was granted right before giving up
+			al.mu.Unlock()
+			p.Release(Ignored)
+		default:
+			al.waiters.Remove(ele)
+			al.mu.Unlock()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Release releases the permit and feeds the outcome of the operation to the limit algorithm.
+// Releasing a permit more than once has no effect.
+func (p *Permit) Release(outcome Outcome) {
+	al := p.limiter
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	if p.released {
+		return
+	}
+	p.released = true
+	al.inflight--
+	if outcome != Ignored {
+		sample := Sample{RTT: al.clock.Now().Sub(p.start), InFlight: p.inflight, Dropped: outcome == Dropped}
+		if limit := al.algorithm.Update(al.limit, sample); limit > 0 {
+			al.limit = limit
+		}
+	}
+	al.grant()
+}
+
+// Limit returns the current concurrency limit.
+func (al *AdaptiveLimiter) Limit() int {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.limit
+}
+
+// InFlight returns the amount of operations currently holding a permit.
+func (al *AdaptiveLimiter) InFlight() int {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+	return al.inflight
+}
@@ -0,0 +1,86 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+	a := NewAIMD(1, 20, 0.5, time.Second)
+	if l := a.Update(10, Sample{RTT: time.Millisecond, InFlight: 10}); l != 11 {
+		t.Errorf("limit was %d, expected %d", l, 11)
+	}
+	if l := a.Update(10, Sample{RTT: time.Millisecond, InFlight: 2}); l != 10 {
+		t.Errorf("limit grew to %d while it wasn't used", l)
+	}
+	if l := a.Update(10, Sample{RTT: time.Millisecond, InFlight: 10, Dropped: true}); l != 5 {
+		t.Errorf("limit was %d, expected %d", l, 5)
+	}
+	if l := a.Update(10, Sample{RTT: 2 * time.Second, InFlight: 10}); l != 5 {
+		t.Errorf("limit was %d, expected %d", l, 5)
+	}
+	if l := a.Update(1, Sample{Dropped: true}); l != 1 {
+		t.Errorf("limit was %d, expected the minimum of %d", l, 1)
+	}
+}
+
+func TestVegas(t *testing.T) {
+	v := NewVegas(1, 1000)
+	limit := 20
+	// latency stays at the no-load latency, so the limit grows
+	for i := 0; i < 5; i++ {
+		limit = v.Update(limit, Sample{RTT: 10 * time.Millisecond, InFlight: limit})
+	}
+	if limit <= 20 {
+		t.Fatalf("limit was %d, expected it to grow beyond %d", limit, 20)
+	}
+	// latency doubles as requests queue up, so the limit shrinks
+	grown := limit
+	for i := 0; i < 5; i++ {
+		limit = v.Update(limit, Sample{RTT: 20 * time.Millisecond, InFlight: limit})
+	}
+	if limit >= grown {
+		t.Errorf("limit was %d, expected it to shrink below %d", limit, grown)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	clk := newFakeClock()
+	al := NewAdaptiveLimiter(2, NewAIMD(1, 10, 0.5, 0))
+	al.SetClock(clk)
+
+	p1, _ := al.Acquire(context.Background())
+	p2, _ := al.Acquire(context.Background())
+	if _, ok := al.TryAcquire(); ok {
+		t.Fatal("permit was acquired beyond the limit")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := al.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("error was %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	acquired := make(chan *Permit)
+	go func() {
+		p, _ := al.Acquire(context.Background())
+		acquired <- p
+	}()
+	clk.Advance(time.Millisecond)
+	p1.Release(Success)
+	p3 := <-acquired
+	if al.Limit() != 3 {
+		t.Errorf("limit was %d, expected %d", al.Limit(), 3)
+	}
+
+	p2.Release(Dropped)
+	p3.Release(Ignored)
+	p3.Release(Ignored)
+	if al.Limit() != 1 {
+		t.Errorf("limit was %d, expected %d", al.Limit(), 1)
+	}
+	if al.InFlight() != 0 {
+		t.Errorf("in flight operations were %d, expected %d", al.InFlight(), 0)
+	}
+}
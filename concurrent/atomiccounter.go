@@ -0,0 +1,142 @@
+package concurrent
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicCounter implements a lock-free counter backed by sync/atomic.
+// The zero value is ready to use.
+type AtomicCounter struct {
+	val int64
+}
+
+// NewAtomicCounter creates a new atomic counter
+func NewAtomicCounter() *AtomicCounter {
+	return &AtomicCounter{}
+}
+
+// Incr increments the counter by one.
+func (ac *AtomicCounter) Incr() {
+	atomic.AddInt64(&ac.val, 1)
+}
+
+// Decr decrements the counter by one.
+func (ac *AtomicCounter) Decr() {
+	atomic.AddInt64(&ac.val, -1)
+}
+
+// Add adds n to the counter and returns the new value.
+func (ac *AtomicCounter) Add(n int64) int64 {
+	return atomic.AddInt64(&ac.val, n)
+}
+
+// Load returns the current value of the counter.
+func (ac *AtomicCounter) Load() int64 {
+	return atomic.LoadInt64(&ac.val)
+}
+
+// Swap sets the counter to n and returns the previous value.
+func (ac *AtomicCounter) Swap(n int64) int64 {
+	return atomic.SwapInt64(&ac.val, n)
+}
+
+// CompareAndSwap sets the counter to new only if it currently holds old.
+// It reports whether the swap was performed.
+func (ac *AtomicCounter) CompareAndSwap(old int64, new int64) bool {
+	return atomic.CompareAndSwapInt64(&ac.val, old, new)
+}
+
+// Reset sets the counter to zero and returns the previous value.
+func (ac *AtomicCounter) Reset() int64 {
+	return atomic.SwapInt64(&ac.val, 0)
+}
+
+// cacheLineSize is the assumed size of a CPU cache line.
+const cacheLineSize = 64
+
+// stripe is a single cell of a StripedCounter padded to a full cache line
+// so that neighbouring stripes don't suffer from false sharing.
+type stripe struct {
+	val int64
+	_   [cacheLineSize - 8]byte
+}
+
+// StripedCounter implements a counter which spreads its value over multiple
+// stripes to reduce contention when many goroutines update it concurrently.
+// Writes are cheap while Load has to sum up all stripes, which makes it a good fit
+// for counters which are updated often but read rarely.
+//
+// Swap and Reset are atomic per stripe: every Add is accounted for exactly once,
+// either in the returned previous value or in the new value of the counter.
+// A striped counter offers no CompareAndSwap as there is no single value to compare against.
+type StripedCounter struct {
+	stripes []stripe
+	mask    uint32
+	next    uint32
+	hints   sync.Pool
+}
+
+// NewStripedCounter creates a new striped counter with the given amount of stripes.
+// The amount is rounded up to the next power of two. If stripes is <= 0,
+// the amount of stripes is derived from GOMAXPROCS.
+func NewStripedCounter(stripes int) *StripedCounter {
+	if stripes <= 0 {
+		stripes = runtime.GOMAXPROCS(0)
+	}
+	size := 1
+	for size < stripes {
+		size <<= 1
+	}
+	sc := &StripedCounter{stripes: make([]stripe, size), mask: uint32(size - 1)}
+	// sync.Pool keeps its items per P, therefore a goroutine usually
+	// gets back the same stripe hint as other goroutines running on the same P.
+	sc.hints.New = func() interface{} {
+		hint := atomic.AddUint32(&sc.next, 1)
+		return &hint
+	}
+	return sc
+}
+
+// Incr increments the counter by one.
+func (sc *StripedCounter) Incr() {
+	sc.Add(1)
+}
+
+// Decr decrements the counter by one.
+func (sc *StripedCounter) Decr() {
+	sc.Add(-1)
+}
+
+// Add adds n to the counter.
+func (sc *StripedCounter) Add(n int64) {
+	hint := sc.hints.Get().(*uint32)
+	atomic.AddInt64(&sc.stripes[*hint&sc.mask].val, n)
+	sc.hints.Put(hint)
+}
+
+// Load returns the sum of all stripes.
+func (sc *StripedCounter) Load() int64 {
+	var sum int64
+	for i := range sc.stripes {
+		sum += atomic.LoadInt64(&sc.stripes[i].val)
+	}
+	return sum
+}
+
+// Swap sets the counter to n and returns the previous value.
+func (sc *StripedCounter) Swap(n int64) int64 {
+	prev := sc.Reset()
+	atomic.AddInt64(&sc.stripes[0].val, n)
+	return prev
+}
+
+// Reset sets the counter to zero and returns the previous value.
+func (sc *StripedCounter) Reset() int64 {
+	var sum int64
+	for i := range sc.stripes {
+		sum += atomic.SwapInt64(&sc.stripes[i].val, 0)
+	}
+	return sum
+}
@@ -0,0 +1,99 @@
+package concurrent
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestAtomicCounter(t *testing.T) {
+	c := NewAtomicCounter()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Incr()
+		}()
+	}
+	wg.Wait()
+	if c.Load() != 100 {
+		t.Errorf("result was %d, expected %d", c.Load(), 100)
+	}
+	if v := c.Add(-95); v != result {
+		t.Errorf("result was %d, expected %d", v, result)
+	}
+	if c.CompareAndSwap(4, 10) {
+		t.Error("compare and swap succeeded with a wrong old value")
+	}
+	if !c.CompareAndSwap(result, 10) {
+		t.Error("compare and swap failed with the correct old value")
+	}
+	if prev := c.Swap(3); prev != 10 {
+		t.Errorf("previous value was %d, expected %d", prev, 10)
+	}
+	if prev := c.Reset(); prev != 3 || c.Load() != 0 {
+		t.Errorf("reset returned %d and left %d, expected 3 and 0", prev, c.Load())
+	}
+}
+
+func TestStripedCounter(t *testing.T) {
+	c := NewStripedCounter(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Incr()
+			}
+		}()
+	}
+	wg.Wait()
+	if c.Load() != 10000 {
+		t.Errorf("result was %d, expected %d", c.Load(), 10000)
+	}
+	if prev := c.Swap(result); prev != 10000 {
+		t.Errorf("previous value was %d, expected %d", prev, 10000)
+	}
+	c.Decr()
+	if prev := c.Reset(); prev != result-1 || c.Load() != 0 {
+		t.Errorf("reset returned %d and left %d, expected %d and 0", prev, c.Load(), result-1)
+	}
+}
+
+func BenchmarkCounter(b *testing.B) {
+	c := NewCounter()
+	defer c.Exit()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr()
+		}
+	})
+}
+
+func BenchmarkSimpleCounter(b *testing.B) {
+	c := NewSimpleCounter()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr()
+		}
+	})
+}
+
+func BenchmarkAtomicCounter(b *testing.B) {
+	c := NewAtomicCounter()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr()
+		}
+	})
+}
+
+func BenchmarkStripedCounter(b *testing.B) {
+	c := NewStripedCounter(0)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Incr()
+		}
+	})
+}
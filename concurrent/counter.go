@@ -74,6 +74,8 @@ func (sc *simplecounter) Decr() {
 }
 
 func (sc *simplecounter) Val() int64 {
+	sc.Lock()
+	defer sc.Unlock()
 	return sc.num
 }
 
@@ -0,0 +1,99 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// GCRA implements the generic cell rate algorithm. It spaces events evenly
+// by one emission interval while tolerating bursts of up to burst events.
+// It only stores the theoretical arrival time of the next event.
+type GCRA struct {
+	mu       sync.Mutex
+	interval time.Duration
+	burst    int
+	tat      time.Time
+	clock    clock.Clock
+}
+
+// NewGCRA creates a new GCRA limiter which allows limit events per period with bursts of up to burst events.
+// This function panics if limit, period or burst are not positive or the period is shorter than
+// limit nanoseconds, which would space events by less than a nanosecond.
+func NewGCRA(limit int, period time.Duration, burst int) *GCRA {
+	if limit <= 0 || period <= 0 || burst <= 0 {
+		panic("limit, period and burst must be positive")
+	}
+	interval := period / time.Duration(limit)
+	if interval <= 0 {
+		panic("period must be at least limit nanoseconds")
+	}
+	return &GCRA{interval: interval, burst: burst, clock: clock.Real}
+}
+
+// SetClock sets the clock used by the limiter.
+func (g *GCRA) SetClock(clk clock.Clock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clock = clk
+	g.tat = time.Time{}
+}
+
+// next returns the theoretical arrival time after admitting an event at the given time
+// and the earliest time at which that event is allowed.
+func (g *GCRA) next(now time.Time) (time.Time, time.Time) {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTAT := tat.Add(g.interval)
+	return newTAT, newTAT.Add(-time.Duration(g.burst) * g.interval)
+}
+
+// Allow reports whether an event may happen now and records it if so. It never blocks.
+func (g *GCRA) Allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	newTAT, allowAt := g.next(now)
+	if now.Before(allowAt) {
+		return false
+	}
+	g.tat = newTAT
+	return true
+}
+
+// Delay returns the time until the next event conforms to the rate.
+func (g *GCRA) Delay() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	_, allowAt := g.next(now)
+	if now.Before(allowAt) {
+		return allowAt.Sub(now)
+	}
+	return 0
+}
+
+// Capacity returns the burst size.
+func (g *GCRA) Capacity() int {
+	return g.burst
+}
+
+// Remaining returns the amount of events which may happen now as a burst.
+func (g *GCRA) Remaining() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+	if !g.tat.After(now) {
+		return g.burst
+	}
+	return g.burst - int((g.tat.Sub(now)+g.interval-1)/g.interval)
+}
+
+// Wait blocks until an event may happen or the given context is done.
+func (g *GCRA) Wait(ctx context.Context) error {
+	return wait(ctx, g.clock, g)
+}
@@ -0,0 +1,184 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// limit defines the rate and burst of a token bucket.
+type limit struct {
+	rate  float64
+	burst int
+}
+
+type keyedentry struct {
+	limiter  *TokenBucket
+	lastSeen time.Time
+}
+
+// KeyedLimiter rate limits events per key, e.g. per client IP or API key.
+// A token bucket is lazily created for each key on first use and evicted
+// after it has been idle for the given TTL.
+type KeyedLimiter struct {
+	mu        sync.Mutex
+	limit     limit
+	ttl       time.Duration
+	entries   map[string]*keyedentry
+	overrides map[string]limit
+	exit      chan struct{}
+	// signals the eviction goroutine to pick up a new clock
+	clockSet chan struct{}
+	clock    clock.Clock
+}
+
+// NewKeyedLimiter creates a new keyed limiter which allows each key the given rate of events
+// per second with the given burst. Keys which weren't used for the given TTL are evicted periodically.
+// If ttl is <= 0, keys are never evicted automatically.
+// The TTL should be at least burst/rate, otherwise an evicted key gets a full bucket earlier than it would have refilled.
+// This function panics if the rate or burst are not positive.
+func NewKeyedLimiter(rate float64, burst int, ttl time.Duration) *KeyedLimiter {
+	if !(rate > 0) || burst <= 0 {
+		panic("rate and burst must be positive")
+	}
+	kl := &KeyedLimiter{
+		limit: limit{rate, burst}, ttl: ttl,
+		entries: make(map[string]*keyedentry), overrides: make(map[string]limit),
+		exit: make(chan struct{}), clockSet: make(chan struct{}, 1), clock: clock.Real,
+	}
+	if ttl > 0 {
+		kl.init()
+	}
+	return kl
+}
+
+// fires up a goroutine which evicts idle keys every TTL.
+func (kl *KeyedLimiter) init() {
+	timer := kl.clock.NewTimer(kl.ttl)
+	go func() {
+	exit:
+		for {
+			select {
+			case <-timer.C():
+				kl.EvictIdle()
+				timer.Reset(kl.ttl)
+			case <-kl.clockSet:
+				timer.Stop()
+				kl.mu.Lock()
+				timer = kl.clock.NewTimer(kl.ttl)
+				kl.mu.Unlock()
+			case <-kl.exit:
+				break exit
+			}
+		}
+		timer.Stop()
+	}()
+}
+
+// SetClock sets the clock used by the keyed limiter and all of its token buckets.
+// Existing token buckets keep their tokens.
+func (kl *KeyedLimiter) SetClock(clk clock.Clock) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	kl.clock = clk
+	for _, e := range kl.entries {
+		e.limiter.switchClock(clk)
+		e.lastSeen = clk.Now()
+	}
+	select {
+	case kl.clockSet <- struct{}{}:
+	default:
+	}
+}
+
+// Exit stops the periodic eviction of idle keys.
+func (kl *KeyedLimiter) Exit() {
+	close(kl.exit)
+}
+
+// Limiter returns the token bucket of the given key, creating it if it doesn't exist.
+func (kl *KeyedLimiter) Limiter(key string) *TokenBucket {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	now := kl.clock.Now()
+	e, has := kl.entries[key]
+	if !has {
+		l, overridden := kl.overrides[key]
+		if !overridden {
+			l = kl.limit
+		}
+		tb := NewTokenBucket(l.rate, l.burst)
+		tb.SetClock(kl.clock)
+		e = &keyedentry{limiter: tb}
+		kl.entries[key] = e
+	}
+	e.lastSeen = now
+	return e.limiter
+}
+
+// Allow reports whether an event for the given key may happen now. It never blocks.
+func (kl *KeyedLimiter) Allow(key string) bool {
+	return kl.Limiter(key).Allow()
+}
+
+// Reserve reserves n events for the given key and returns the delay after which they may happen.
+func (kl *KeyedLimiter) Reserve(key string, n int) (time.Duration, error) {
+	return kl.Limiter(key).Reserve(n)
+}
+
+// Wait blocks until an event for the given key may happen or the given context is done.
+func (kl *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return kl.Limiter(key).Wait(ctx)
+}
+
+// SetOverride sets a different rate and burst for the given key.
+// An existing token bucket of the key is replaced.
+// This method panics if the rate or burst are not positive.
+func (kl *KeyedLimiter) SetOverride(key string, rate float64, burst int) {
+	if !(rate > 0) || burst <= 0 {
+		panic("rate and burst must be positive")
+	}
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	kl.overrides[key] = limit{rate, burst}
+	delete(kl.entries, key)
+}
+
+// RemoveOverride reverts the given key to the default rate and burst.
+func (kl *KeyedLimiter) RemoveOverride(key string) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	if _, has := kl.overrides[key]; !has {
+		return
+	}
+	delete(kl.overrides, key)
+	delete(kl.entries, key)
+}
+
+// EvictIdle removes all keys which weren't used for the TTL and returns the amount of evicted keys.
+// If the TTL is <= 0, no keys are evicted.
+func (kl *KeyedLimiter) EvictIdle() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	if kl.ttl <= 0 {
+		return 0
+	}
+	now := kl.clock.Now()
+	evicted := 0
+	for key, e := range kl.entries {
+		if now.Sub(e.lastSeen) >= kl.ttl {
+			delete(kl.entries, key)
+			evicted++
+		}
+	}
+	return evicted
+}
+
+// Len returns the amount of keys which currently hold a token bucket.
+func (kl *KeyedLimiter) Len() int {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+	return len(kl.entries)
+}
@@ -0,0 +1,91 @@
+package concurrent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter(t *testing.T) {
+	clk := newFakeClock()
+	kl := NewKeyedLimiter(1, 2, time.Minute)
+	defer kl.Exit()
+	kl.SetClock(clk)
+	kl.SetOverride("vip", 1, 5)
+	// the eviction goroutine switched to the injected clock
+	clk.BlockUntil(1)
+
+	for i := 0; i < 2; i++ {
+		if !kl.Allow("10.0.0.1") {
+			t.Fatalf("event %d was not allowed within the burst", i)
+		}
+	}
+	if kl.Allow("10.0.0.1") {
+		t.Error("event was allowed after the burst was used up")
+	}
+	// other keys are limited independently
+	if !kl.Allow("10.0.0.2") {
+		t.Error("event of an unrelated key was not allowed")
+	}
+	for i := 0; i < 5; i++ {
+		if !kl.Allow("vip") {
+			t.Fatalf("event %d of the overridden key was not allowed", i)
+		}
+	}
+	if kl.Len() != 3 {
+		t.Errorf("keys were %d, expected %d", kl.Len(), 3)
+	}
+
+	clk.Advance(30 * time.Second)
+	kl.Allow("vip")
+	// evicts the keys idle for the TTL and waits again
+	clk.Advance(30 * time.Second)
+	clk.BlockUntil(1)
+	if kl.Len() != 1 {
+		t.Errorf("keys were %d, expected %d", kl.Len(), 1)
+	}
+}
+
+func TestKeyedLimiterSetClock(t *testing.T) {
+	kl := NewKeyedLimiter(1, 2, 0)
+	kl.SetClock(newFakeClock())
+	kl.Allow("10.0.0.1")
+	kl.Allow("10.0.0.1")
+
+	// switching the clock doesn't refill the bucket
+	kl.SetClock(newFakeClock())
+	if kl.Allow("10.0.0.1") {
+		t.Error("event was allowed after the burst was used up")
+	}
+}
+
+func TestKeyedLimiterWithoutTTL(t *testing.T) {
+	clk := newFakeClock()
+	kl := NewKeyedLimiter(1, 2, 0)
+	kl.SetClock(clk)
+	kl.Allow("10.0.0.1")
+
+	clk.Advance(time.Hour)
+	if evicted := kl.EvictIdle(); evicted != 0 {
+		t.Errorf("evicted keys were %d, expected %d", evicted, 0)
+	}
+	if kl.Len() != 1 {
+		t.Errorf("keys were %d, expected %d", kl.Len(), 1)
+	}
+}
+
+func TestKeyedLimiterEviction(t *testing.T) {
+	clk := newFakeClock()
+	kl := NewKeyedLimiter(1, 2, time.Minute)
+	defer kl.Exit()
+	kl.SetClock(clk)
+	kl.Allow("10.0.0.1")
+
+	// the eviction goroutine waits on the injected clock
+	clk.BlockUntil(1)
+	clk.Advance(time.Minute)
+	// it waits again once the idle key was evicted
+	clk.BlockUntil(1)
+	if kl.Len() != 0 {
+		t.Errorf("keys were %d, expected %d", kl.Len(), 0)
+	}
+}
@@ -0,0 +1,43 @@
+package concurrent
+
+import (
+	"context"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// Limiter is implemented by the rate limiters of this package.
+type Limiter interface {
+	// Allow reports whether an event may happen now and records it if so. It never blocks.
+	Allow() bool
+	// Delay returns the time until the next event would be allowed.
+	Delay() time.Duration
+	// Wait blocks until an event may happen or the given context is done.
+	Wait(ctx context.Context) error
+}
+
+// Quota is implemented by limiters which can report how many events they let pass.
+type Quota interface {
+	// Capacity returns the maximum amount of events which may happen at once.
+	Capacity() int
+	// Remaining returns the amount of events which may happen now.
+	Remaining() int
+}
+
+// wait blocks until the given limiter allows an event or the given context is done.
+func wait(ctx context.Context, clk clock.Clock, l Limiter) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if l.Allow() {
+			return nil
+		}
+		select {
+		case <-clk.After(l.Delay()):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
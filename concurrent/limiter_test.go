@@ -0,0 +1,192 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+var (
+	_ Limiter = &simpleratelimiter{}
+	_ Limiter = &TokenBucket{}
+	_ Limiter = &SlidingWindowLog{}
+	_ Limiter = &SlidingWindowCounter{}
+	_ Limiter = &GCRA{}
+
+	_ Quota = &simpleratelimiter{}
+	_ Quota = &TokenBucket{}
+	_ Quota = &SlidingWindowLog{}
+	_ Quota = &SlidingWindowCounter{}
+	_ Quota = &GCRA{}
+)
+
+func newFakeClock() *clock.Fake {
+	return clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// allowed returns how many of n events the given limiter lets pass.
+func allowed(l Limiter, n int) int {
+	passed := 0
+	for i := 0; i < n; i++ {
+		if l.Allow() {
+			passed++
+		}
+	}
+	return passed
+}
+
+// TestLimiterWindowBoundary sends a burst right before and right after a window boundary.
+// Only the fixed window limiter lets twice the rate pass.
+func TestLimiterWindowBoundary(t *testing.T) {
+	fixed := NewRateLimier(10, time.Second)
+	defer fixed.Exit()
+	swl := NewSlidingWindowLog(10, time.Second)
+	swc := NewSlidingWindowCounter(10, time.Second)
+	gcra := NewGCRA(10, time.Second, 10)
+
+	limiters := []struct {
+		name     string
+		limiter  Limiter
+		setClock func(clock.Clock)
+		max      int
+	}{
+		{"fixed window", fixed, fixed.SetClock, 20},
+		{"sliding window log", swl, swl.SetClock, 10},
+		{"sliding window counter", swc, swc.SetClock, 11},
+		{"gcra", gcra, gcra.SetClock, 12},
+	}
+	for _, l := range limiters {
+		clk := newFakeClock()
+		l.setClock(clk)
+		clk.Advance(900 * time.Millisecond)
+		passed := allowed(l.limiter, 20)
+		clk.Advance(200 * time.Millisecond)
+		passed += allowed(l.limiter, 20)
+		if passed != l.max {
+			t.Errorf("%s let %d events pass, expected %d", l.name, passed, l.max)
+		}
+	}
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	clk := newFakeClock()
+	swl := NewSlidingWindowLog(3, time.Second)
+	swl.SetClock(clk)
+
+	for i := 0; i < 3; i++ {
+		swl.Allow()
+		clk.Advance(100 * time.Millisecond)
+	}
+	if swl.Allow() {
+		t.Fatal("event was allowed with a full window")
+	}
+	if swl.Remaining() != 0 {
+		t.Errorf("remaining events were %d, expected %d", swl.Remaining(), 0)
+	}
+	if d := swl.Delay(); d != 700*time.Millisecond {
+		t.Errorf("delay was %v, expected %v", d, 700*time.Millisecond)
+	}
+	clk.Advance(700 * time.Millisecond)
+	if !swl.Allow() {
+		t.Error("event was not allowed after the oldest event expired")
+	}
+}
+
+func TestSlidingWindowCounter(t *testing.T) {
+	clk := newFakeClock()
+	swc := NewSlidingWindowCounter(10, time.Second)
+	swc.SetClock(clk)
+
+	if passed := allowed(swc, 20); passed != 10 {
+		t.Fatalf("%d events passed, expected %d", passed, 10)
+	}
+	// a quarter into the next window, the previous window still weighs 7.5 events
+	clk.Advance(1250 * time.Millisecond)
+	if passed := allowed(swc, 20); passed != 2 {
+		t.Errorf("%d events passed, expected %d", passed, 2)
+	}
+	if d := swc.Delay(); d != 50*time.Millisecond {
+		t.Errorf("delay was %v, expected %v", d, 50*time.Millisecond)
+	}
+}
+
+func TestGCRA(t *testing.T) {
+	clk := newFakeClock()
+	g := NewGCRA(10, time.Second, 3)
+	g.SetClock(clk)
+	g.Allow()
+	if g.Remaining() != 2 {
+		t.Errorf("remaining events were %d, expected %d", g.Remaining(), 2)
+	}
+
+	g = NewGCRA(10, time.Second, 1)
+	g.SetClock(clk)
+
+	// without a burst, events are spaced by the emission interval
+	if passed := allowed(g, 5); passed != 1 {
+		t.Fatalf("%d events passed, expected %d", passed, 1)
+	}
+	if d := g.Delay(); d != 100*time.Millisecond {
+		t.Errorf("delay was %v, expected %v", d, 100*time.Millisecond)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if !g.Allow() {
+		t.Error("event was not allowed after the emission interval")
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	clk := newFakeClock()
+	swl := NewSlidingWindowLog(1, time.Second)
+	swl.SetClock(clk)
+	swl.Allow()
+
+	done := make(chan error)
+	go func() {
+		done <- swl.Wait(context.Background())
+	}()
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := swl.Wait(ctx); err != context.Canceled {
+		t.Errorf("error was %v, expected %v", err, context.Canceled)
+	}
+}
+
+func TestLimiterInvalidArguments(t *testing.T) {
+	constructors := map[string]func(){
+		"fixed window":           func() { NewRateLimier(10, 0) },
+		"sliding window log":     func() { NewSlidingWindowLog(0, time.Second) },
+		"sliding window counter": func() { NewSlidingWindowCounter(10, -time.Second) },
+		"gcra":                   func() { NewGCRA(0, time.Second, 1) },
+		"gcra interval":          func() { NewGCRA(1001, time.Microsecond, 1) },
+		"token bucket rate":      func() { NewTokenBucket(0, 1) },
+		"token bucket burst":     func() { NewTokenBucket(1, 0) },
+	}
+	for name, construct := range constructors {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected a panic on invalid arguments", name)
+				}
+			}()
+			construct()
+		}()
+	}
+}
+
+func TestRateLimiterExitTwice(t *testing.T) {
+	rl := NewRateLimier(10, time.Second)
+	rl.Exit()
+	rl.Exit()
+	if rl.Allow() {
+		t.Error("event was allowed after exiting")
+	}
+}
@@ -3,6 +3,8 @@ package concurrent
 import (
 	"sync"
 	"time"
+
+	"github.com/luca-moser/belt/clock"
 )
 
 // NewPipeline creates a new pipeline
@@ -10,7 +12,7 @@ func NewPipeline() *pipeline {
 	p := &pipeline{
 		make(chan interface{}),
 		make(chan struct{}, 1), make(chan struct{}, 1), make(chan struct{}, 1),
-		false, false, make([]*pipe, 0),
+		false, false, make([]*pipe, 0), clock.Real,
 	}
 	return p
 }
@@ -24,6 +26,7 @@ type pipeline struct {
 	isStopped bool
 	isPaused  bool
 	pipes     []*pipe
+	clock     clock.Clock
 }
 
 // Adds a pipe to the end of the pipeline
@@ -34,8 +37,17 @@ func (p *pipeline) AddPipe(name string, buffer int, f func(interface{}) interfac
 		lastPipe := p.pipes[len(p.pipes)-1]
 		lastPipe.next = pipe.receive
 	}
-	p.pipes = append(p.pipes, &pipe)
-	return &pipe
+	p.pipes = append(p.pipes, pipe)
+	return pipe
+}
+
+// SetClock sets the clock used to measure the worktime of the pipes.
+// It must be called before the pipeline is started.
+func (p *pipeline) SetClock(clk clock.Clock) {
+	p.clock = clk
+	for _, pipe := range p.pipes {
+		pipe.clock = clk
+	}
 }
 
 // Start starts the pipeline for execution.
@@ -103,11 +115,11 @@ func (p *pipeline) Resume() {
 	p.resume <- struct{}{}
 }
 
-func (p *pipeline) newpipe(name string, buffer int, f func(interface{}) interface{}) pipe {
-	pi := pipe{
+func (p *pipeline) newpipe(name string, buffer int, f func(interface{}) interface{}) *pipe {
+	pi := &pipe{
 		f, make(chan interface{}, buffer), nil,
 		make(chan struct{}, 1), make(chan struct{}, 1), make(chan struct{}, 1),
-		name, false, make(chan pipeexecution, buffer), 0, sync.Mutex{},
+		name, false, make(chan pipeexecution, buffer), 0, sync.Mutex{}, p.clock,
 	}
 	return pi
 }
@@ -126,6 +138,7 @@ type pipe struct {
 	measurement chan pipeexecution
 	processed   int64
 	mu          sync.Mutex
+	clock       clock.Clock
 }
 
 type pipeexecution struct {
@@ -165,9 +178,9 @@ func (p *pipe) init() {
 			case val := <-p.receive:
 				var res interface{}
 				if p.measure {
-					s := time.Now()
+					s := p.clock.Now()
 					res = p.f(val)
-					p.measurement <- pipeexecution{p.name, p.processed, res, time.Since(s)}
+					p.measurement <- pipeexecution{p.name, p.processed, res, p.clock.Now().Sub(s)}
 				} else {
 					res = p.f(val)
 				}
@@ -22,19 +22,37 @@ func TestPipline(t *testing.T) {
 			feedChannel <- i
 		}
 	}()
-	anyValueWrong := false
-	anyPass := false
-	go func() {
-		for i := 0; i < 10; i++ {
-			num := <-out
+	for i := 0; i < 10; i++ {
+		select {
+		case num := <-out:
 			if num != i+10 {
-				anyValueWrong = true
+				t.Errorf("result was %v, expected %d", num, i+10)
 			}
-			anyPass = true
+		case <-time.After(time.Duration(1) * time.Second):
+			t.Fatal("pipeline didn't produce all results")
 		}
+	}
+}
+
+func TestPipelineMeasure(t *testing.T) {
+	clk := newFakeClock()
+	pipeline := NewPipeline()
+	pipeline.SetClock(clk)
+	pipe := pipeline.AddPipe("slow", 1, func(input interface{}) interface{} {
+		clk.Advance(5 * time.Millisecond)
+		return input
+	})
+	measurement := pipe.Measure()
+	feedChannel := make(chan interface{})
+	out := pipeline.Start(feedChannel)
+	go func() {
+		feedChannel <- result
 	}()
-	<-time.After(time.Duration(1) * time.Second)
-	if anyValueWrong || !anyPass {
-		t.Fail()
+	exec := <-measurement
+	if exec.Name != "slow" || exec.Result != result || exec.Delta != 5*time.Millisecond {
+		t.Errorf("measurement was %+v, expected a delta of %v", exec, 5*time.Millisecond)
+	}
+	if num := <-out; num != result {
+		t.Errorf("result was %v, expected %d", num, result)
 	}
 }
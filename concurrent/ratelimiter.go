@@ -1,48 +1,116 @@
 package concurrent
 
-import "time"
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
 
+	"github.com/luca-moser/belt/clock"
+)
+
+// simpleratelimiter allows rate events per fixed window of the given duration.
 type simpleratelimiter struct {
+	mu       sync.Mutex
 	rate     int
 	duration time.Duration
-	limiter  chan struct{}
+	start    time.Time
+	passed   int
 	exit     chan struct{}
+	exitOnce sync.Once
+	clock    clock.Clock
+}
+
+// advance starts a new window if the current one is over.
+func (rl *simpleratelimiter) advance(now time.Time) {
+	if elapsed := now.Sub(rl.start); elapsed >= rl.duration {
+		rl.start = rl.start.Add(elapsed - elapsed%rl.duration)
+		rl.passed = 0
+	}
+}
+
+// SetClock sets the clock used by the rate limiter and starts a new window.
+func (rl *simpleratelimiter) SetClock(clk clock.Clock) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.clock = clk
+	rl.start = clk.Now()
+	rl.passed = 0
 }
 
-func (rl *simpleratelimiter) init() {
-	go func() {
-	exit:
-		for {
-			select {
-			case <-time.After(rl.duration):
-			case <-rl.exit:
-				break exit
-			}
-			for i := 0; i < rl.rate; i++ {
-				select {
-				case <-rl.limiter:
-				case <-rl.exit:
-					break exit
-				}
-			}
-		}
-	}()
-}
-
-// Exit tells the rate limiter to stop listening for any further channel messages.
-// Calls to TryPass() after calling this function will result in a deadlock.
+// Exit tells the rate limiter to stop letting events pass.
+// Calls to TryPass() after calling this function will block forever. Calling it again has no effect.
 func (rl *simpleratelimiter) Exit() {
-	rl.exit <- struct{}{}
+	rl.exitOnce.Do(func() {
+		close(rl.exit)
+	})
+}
+
+// Allow reports whether an event may pass during the current window. It never blocks.
+func (rl *simpleratelimiter) Allow() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	select {
+	case <-rl.exit:
+		return false
+	default:
+	}
+	rl.advance(rl.clock.Now())
+	if rl.passed >= rl.rate {
+		return false
+	}
+	rl.passed++
+	return true
+}
+
+// Delay returns the time until the next window starts if the current window is used up.
+// After Exit() was called, no event will ever pass again.
+func (rl *simpleratelimiter) Delay() time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	select {
+	case <-rl.exit:
+		return time.Duration(math.MaxInt64)
+	default:
+	}
+	now := rl.clock.Now()
+	rl.advance(now)
+	if rl.passed < rl.rate {
+		return 0
+	}
+	return rl.start.Add(rl.duration).Sub(now)
+}
+
+// Capacity returns the amount of events which may pass per window.
+func (rl *simpleratelimiter) Capacity() int {
+	return rl.rate
+}
+
+// Remaining returns the amount of events which may still pass during the current window.
+func (rl *simpleratelimiter) Remaining() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.advance(rl.clock.Now())
+	return rl.rate - rl.passed
+}
+
+// Wait blocks until an event may pass or the given context is done.
+func (rl *simpleratelimiter) Wait(ctx context.Context) error {
+	return wait(ctx, rl.clock, rl)
 }
 
 // TryPass tries to get a passthrough during the current cycle and blocks until it can pass.
 func (rl *simpleratelimiter) TryPass() {
-	rl.limiter <- struct{}{}
+	rl.Wait(context.Background())
 }
 
 // NewRateLimier returns a rate limiter.
+// This function panics if the duration is not positive.
 func NewRateLimier(rate int, duration time.Duration) *simpleratelimiter {
-	r := &simpleratelimiter{rate, duration, make(chan struct{}), make(chan struct{})}
-	r.init()
+	if duration <= 0 {
+		panic("duration must be positive")
+	}
+	r := &simpleratelimiter{rate: rate, duration: duration, exit: make(chan struct{})}
+	r.SetClock(clock.Real)
 	return r
 }
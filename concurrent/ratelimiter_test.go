@@ -6,14 +6,32 @@ import (
 )
 
 func TestRateLimiter(t *testing.T) {
-	s := time.Now()
+	clk := newFakeClock()
+	s := clk.Now()
 	limiter := NewRateLimier(500, time.Duration(1)*time.Second)
+	limiter.SetClock(clk)
 	defer limiter.Exit()
-	for i := 0; i < 1000; i++ {
-		limiter.TryPass()
-	}
-	// should be 4, but we allow 100ms tolerance
-	if time.Since(s).Seconds() > 2.1 {
-		t.Error("rate limiter took too long")
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			limiter.TryPass()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			// the second half has to wait for the next window
+			if elapsed := clk.Now().Sub(s); elapsed != time.Second {
+				t.Errorf("rate limiter took %v, expected %v", elapsed, time.Second)
+			}
+			return
+		default:
+			if clk.Waiters() > 0 {
+				clk.Advance(time.Second)
+			}
+			time.Sleep(time.Millisecond)
+		}
 	}
 }
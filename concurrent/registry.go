@@ -0,0 +1,186 @@
+package concurrent
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Gauge implements a lock-free float64 value which can be set and adjusted.
+// The zero value is ready to use.
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add adds delta to the gauge and returns the new value.
+func (g *Gauge) Add(delta float64) float64 {
+	for {
+		old := atomic.LoadUint64(&g.bits)
+		v := math.Float64frombits(old) + delta
+		if atomic.CompareAndSwapUint64(&g.bits, old, math.Float64bits(v)) {
+			return v
+		}
+	}
+}
+
+// Load returns the current value of the gauge.
+func (g *Gauge) Load() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Snapshot holds the values of all counters and gauges of a registry at a point in time.
+type Snapshot struct {
+	Counters map[string]int64   `json:"counters"`
+	Gauges   map[string]float64 `json:"gauges"`
+}
+
+// Registry holds named counters and gauges, which are created on demand.
+// Counters and gauges are identified by their name and optional labels.
+type Registry struct {
+	mu       sync.Mutex
+	counters map[string]*AtomicCounter
+	gauges   map[string]*Gauge
+}
+
+// NewRegistry creates a new registry
+func NewRegistry() *Registry {
+	return &Registry{counters: make(map[string]*AtomicCounter), gauges: make(map[string]*Gauge)}
+}
+
+// DefaultRegistry is the registry used by packages which don't manage their own.
+var DefaultRegistry = NewRegistry()
+
+// registryKey builds the identifier of a counter or gauge in the form name{k1="v1",k2="v2"}.
+// Labels are given as key/value pairs and are sorted by key.
+// This function panics if an odd amount of labels is given.
+func registryKey(name string, labels []string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	if len(labels)%2 != 0 {
+		panic(fmt.Sprintf("labels of %s must be given as key/value pairs", name))
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%s{%s}", name, strings.Join(pairs, ","))
+}
+
+// Counter returns the counter with the given name and labels, creating it if it doesn't exist.
+// Labels are given as key/value pairs, e.g. Counter("requests", "method", "GET").
+func (r *Registry) Counter(name string, labels ...string) *AtomicCounter {
+	k := registryKey(name, labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, has := r.counters[k]
+	if !has {
+		c = NewAtomicCounter()
+		r.counters[k] = c
+	}
+	return c
+}
+
+// Gauge returns the gauge with the given name and labels, creating it if it doesn't exist.
+// Labels are given as key/value pairs, e.g. Gauge("connections", "pool", "main").
+func (r *Registry) Gauge(name string, labels ...string) *Gauge {
+	k := registryKey(name, labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, has := r.gauges[k]
+	if !has {
+		g = &Gauge{}
+		r.gauges[k] = g
+	}
+	return g
+}
+
+// Unregister removes the counter and gauge with the given name and labels.
+func (r *Registry) Unregister(name string, labels ...string) {
+	k := registryKey(name, labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.counters, k)
+	delete(r.gauges, k)
+}
+
+// Names returns the sorted identifiers of all registered counters and gauges.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := []string{}
+	for n := range r.counters {
+		names = append(names, n)
+	}
+	for n := range r.gauges {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) snapshot(reset bool) Snapshot {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Snapshot{make(map[string]int64, len(r.counters)), make(map[string]float64, len(r.gauges))}
+	for n, c := range r.counters {
+		if reset {
+			s.Counters[n] = c.Reset()
+		} else {
+			s.Counters[n] = c.Load()
+		}
+	}
+	for n, g := range r.gauges {
+		s.Gauges[n] = g.Load()
+	}
+	return s
+}
+
+// Snapshot returns the current values of all counters and gauges.
+func (r *Registry) Snapshot() Snapshot {
+	return r.snapshot(false)
+}
+
+// SnapshotAndReset returns the current values of all counters and gauges and resets all counters to zero.
+// Each counter is read and reset atomically, so no increment is lost between two snapshots.
+// Gauges are not reset as they represent a current state.
+func (r *Registry) SnapshotAndReset() Snapshot {
+	return r.snapshot(true)
+}
+
+// MarshalJSON encodes a snapshot of the registry as JSON.
+func (r *Registry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Snapshot())
+}
+
+// WriteJSON writes a snapshot of the registry as JSON to the given writer.
+func (r *Registry) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r.Snapshot())
+}
+
+// String returns the registry as JSON. It implements expvar.Var.
+func (r *Registry) String() string {
+	bytes, err := r.MarshalJSON()
+	if err != nil {
+		return "{}"
+	}
+	return string(bytes)
+}
+
+// Publish exposes the registry under the given name via the expvar package.
+// Like expvar.Publish, this method panics if the name is already in use.
+func (r *Registry) Publish(name string) {
+	expvar.Publish(name, r)
+}
@@ -0,0 +1,68 @@
+package concurrent
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("requests", "method", "GET").Add(3)
+	r.Counter("requests", "method", "GET").Incr()
+	r.Counter("requests", "method", "POST", "code", "200").Incr()
+	r.Gauge("connections").Set(2.5)
+
+	names := r.Names()
+	expected := []string{"connections", `requests{code="200",method="POST"}`, `requests{method="GET"}`}
+	if len(names) != len(expected) {
+		t.Fatalf("names were %v, expected %v", names, expected)
+	}
+	for i := range names {
+		if names[i] != expected[i] {
+			t.Errorf("name was %s, expected %s", names[i], expected[i])
+		}
+	}
+
+	s := r.SnapshotAndReset()
+	if s.Counters[`requests{method="GET"}`] != 4 {
+		t.Errorf("counter was %d, expected %d", s.Counters[`requests{method="GET"}`], 4)
+	}
+	if s.Gauges["connections"] != 2.5 {
+		t.Errorf("gauge was %f, expected %f", s.Gauges["connections"], 2.5)
+	}
+	if v := r.Counter("requests", "method", "GET").Load(); v != 0 {
+		t.Errorf("counter was %d after reset, expected %d", v, 0)
+	}
+	if v := r.Gauge("connections").Load(); v != 2.5 {
+		t.Errorf("gauge was %f after reset, expected %f", v, 2.5)
+	}
+}
+
+func TestRegistryJSON(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("jobs").Add(result)
+	if expvar.Get("belt_test_registry") == nil {
+		r.Publish("belt_test_registry")
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s := Snapshot{}
+	if err := json.Unmarshal(buf.Bytes(), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Counters["jobs"] != result {
+		t.Errorf("counter was %d, expected %d", s.Counters["jobs"], result)
+	}
+
+	if err := json.Unmarshal([]byte(expvar.Get("belt_test_registry").String()), &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Counters["jobs"] != result {
+		t.Errorf("expvar counter was %d, expected %d", s.Counters["jobs"], result)
+	}
+}
@@ -0,0 +1,200 @@
+package concurrent
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// SlidingWindowLog allows limit events within any window of the given duration.
+// It keeps a log of the timestamps of all allowed events within the window,
+// which makes it exact but uses memory proportional to the limit.
+type SlidingWindowLog struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	log    []time.Time
+	clock  clock.Clock
+}
+
+// NewSlidingWindowLog creates a new sliding window log limiter.
+// This function panics if the limit or window are not positive.
+func NewSlidingWindowLog(limit int, window time.Duration) *SlidingWindowLog {
+	if limit <= 0 || window <= 0 {
+		panic("limit and window must be positive")
+	}
+	return &SlidingWindowLog{limit: limit, window: window, log: make([]time.Time, 0, limit), clock: clock.Real}
+}
+
+// SetClock sets the clock used by the limiter.
+func (swl *SlidingWindowLog) SetClock(clk clock.Clock) {
+	swl.mu.Lock()
+	defer swl.mu.Unlock()
+	swl.clock = clk
+}
+
+// expire removes all timestamps which are no longer within the window.
+func (swl *SlidingWindowLog) expire(now time.Time) {
+	i := 0
+	for i < len(swl.log) && now.Sub(swl.log[i]) >= swl.window {
+		i++
+	}
+	swl.log = append(swl.log[:0], swl.log[i:]...)
+}
+
+// Allow reports whether an event may happen now and records it if so. It never blocks.
+func (swl *SlidingWindowLog) Allow() bool {
+	swl.mu.Lock()
+	defer swl.mu.Unlock()
+	now := swl.clock.Now()
+	swl.expire(now)
+	if len(swl.log) >= swl.limit {
+		return false
+	}
+	swl.log = append(swl.log, now)
+	return true
+}
+
+// Delay returns the time until the oldest event in the window expires if the window is full.
+func (swl *SlidingWindowLog) Delay() time.Duration {
+	swl.mu.Lock()
+	defer swl.mu.Unlock()
+	now := swl.clock.Now()
+	swl.expire(now)
+	if len(swl.log) < swl.limit {
+		return 0
+	}
+	return swl.log[len(swl.log)-swl.limit].Add(swl.window).Sub(now)
+}
+
+// Capacity returns the amount of events which may happen within the window.
+func (swl *SlidingWindowLog) Capacity() int {
+	return swl.limit
+}
+
+// Remaining returns the amount of events which may still happen within the current window.
+func (swl *SlidingWindowLog) Remaining() int {
+	swl.mu.Lock()
+	defer swl.mu.Unlock()
+	swl.expire(swl.clock.Now())
+	return swl.limit - len(swl.log)
+}
+
+// Wait blocks until an event may happen or the given context is done.
+func (swl *SlidingWindowLog) Wait(ctx context.Context) error {
+	return wait(ctx, swl.clock, swl)
+}
+
+// SlidingWindowCounter approximates a sliding window by weighting the count of the
+// previous fixed window by how much of it still overlaps with the sliding window.
+// It only keeps two counters, but assumes events of the previous window were evenly distributed.
+type SlidingWindowCounter struct {
+	mu       sync.Mutex
+	limit    int
+	window   time.Duration
+	start    time.Time
+	previous int
+	current  int
+	clock    clock.Clock
+}
+
+// NewSlidingWindowCounter creates a new sliding window counter limiter.
+// This function panics if the limit or window are not positive.
+func NewSlidingWindowCounter(limit int, window time.Duration) *SlidingWindowCounter {
+	if limit <= 0 || window <= 0 {
+		panic("limit and window must be positive")
+	}
+	swc := &SlidingWindowCounter{limit: limit, window: window}
+	swc.SetClock(clock.Real)
+	return swc
+}
+
+// SetClock sets the clock used by the limiter and starts a new window.
+func (swc *SlidingWindowCounter) SetClock(clk clock.Clock) {
+	swc.mu.Lock()
+	defer swc.mu.Unlock()
+	swc.clock = clk
+	swc.start = clk.Now()
+	swc.previous, swc.current = 0, 0
+}
+
+// advance moves to the fixed window containing the given time.
+func (swc *SlidingWindowCounter) advance(now time.Time) {
+	elapsed := now.Sub(swc.start)
+	if elapsed < swc.window {
+		return
+	}
+	if elapsed < 2*swc.window {
+		swc.previous = swc.current
+	} else {
+		swc.previous = 0
+	}
+	swc.current = 0
+	swc.start = swc.start.Add(elapsed - elapsed%swc.window)
+}
+
+// estimate returns the weighted amount of events within the sliding window ending at the given time.
+func (swc *SlidingWindowCounter) estimate(now time.Time) float64 {
+	overlap := 1 - float64(now.Sub(swc.start))/float64(swc.window)
+	return float64(swc.previous)*overlap + float64(swc.current)
+}
+
+// Allow reports whether an event may happen now and records it if so. It never blocks.
+func (swc *SlidingWindowCounter) Allow() bool {
+	swc.mu.Lock()
+	defer swc.mu.Unlock()
+	now := swc.clock.Now()
+	swc.advance(now)
+	if swc.estimate(now)+1 > float64(swc.limit) {
+		return false
+	}
+	swc.current++
+	return true
+}
+
+// Delay returns the time until the weighted count dropped enough to allow the next event.
+func (swc *SlidingWindowCounter) Delay() time.Duration {
+	swc.mu.Lock()
+	defer swc.mu.Unlock()
+	now := swc.clock.Now()
+	swc.advance(now)
+	excess := swc.estimate(now) + 1 - float64(swc.limit)
+	if excess <= 0 {
+		return 0
+	}
+	end := swc.start.Add(swc.window)
+	if swc.previous == 0 || float64(swc.current)+1 > float64(swc.limit) {
+		// only the start of the next window can free up capacity
+		return end.Sub(now)
+	}
+	// the previous window's weight decays linearly until the end of the current window
+	d := time.Duration(excess / float64(swc.previous) * float64(swc.window))
+	if remaining := end.Sub(now); d > remaining {
+		d = remaining
+	}
+	return d
+}
+
+// Capacity returns the amount of events which may happen within the window.
+func (swc *SlidingWindowCounter) Capacity() int {
+	return swc.limit
+}
+
+// Remaining returns the amount of events which may still happen within the current window.
+func (swc *SlidingWindowCounter) Remaining() int {
+	swc.mu.Lock()
+	defer swc.mu.Unlock()
+	now := swc.clock.Now()
+	swc.advance(now)
+	if remaining := int(float64(swc.limit) - swc.estimate(now)); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Wait blocks until an event may happen or the given context is done.
+func (swc *SlidingWindowCounter) Wait(ctx context.Context) error {
+	return wait(ctx, swc.clock, swc)
+}
@@ -0,0 +1,194 @@
+package concurrent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// ErrBurstExceeded is returned when more tokens are requested at once than the bucket can hold.
+type ErrBurstExceeded struct {
+	N     int
+	Burst int
+}
+
+func (e ErrBurstExceeded) Error() string {
+	return fmt.Sprintf("requested %d tokens exceed the burst capacity of %d", e.N, e.Burst)
+}
+
+// ErrInvalidTokens is returned when a non-positive amount of tokens is requested.
+type ErrInvalidTokens struct {
+	N int
+}
+
+func (e ErrInvalidTokens) Error() string {
+	return fmt.Sprintf("requested %d tokens, expected at least one", e.N)
+}
+
+// TokenBucket implements a token bucket rate limiter.
+// The bucket holds up to burst tokens and is refilled continuously at the given rate.
+// Every event takes a token out of the bucket, events are only allowed while tokens are available.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+// NewTokenBucket creates a new token bucket which refills at the given rate of tokens per second
+// and holds at most burst tokens. The rate may be fractional, e.g. 0.5 for one token every two seconds.
+// The bucket starts out full.
+// This function panics if the rate or burst are not positive.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if !(rate > 0) || burst <= 0 {
+		panic("rate and burst must be positive")
+	}
+	tb := &TokenBucket{rate: rate, burst: burst, tokens: float64(burst)}
+	tb.SetClock(clock.Real)
+	return tb
+}
+
+// SetClock sets the clock used by the token bucket and fills up the bucket.
+func (tb *TokenBucket) SetClock(clk clock.Clock) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.clock = clk
+	tb.last = clk.Now()
+	tb.tokens = float64(tb.burst)
+}
+
+// switchClock switches the bucket to the given clock, keeping its tokens.
+func (tb *TokenBucket) switchClock(clk clock.Clock) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	tb.clock = clk
+	tb.last = clk.Now()
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (tb *TokenBucket) refill(t time.Time) {
+	if elapsed := t.Sub(tb.last); elapsed > 0 {
+		tb.tokens = math.Min(float64(tb.burst), tb.tokens+elapsed.Seconds()*tb.rate)
+		tb.last = t
+	}
+}
+
+// durationFor returns the time it takes to refill the given amount of tokens.
+func (tb *TokenBucket) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(tokens / tb.rate * float64(time.Second)))
+}
+
+// Allow reports whether an event may happen now and takes a token if so. It never blocks.
+func (tb *TokenBucket) Allow() bool {
+	return tb.AllowN(1)
+}
+
+// AllowN reports whether n events may happen now and takes n tokens if so. It never blocks.
+// A non-positive n is never allowed.
+func (tb *TokenBucket) AllowN(n int) bool {
+	if n <= 0 {
+		return false
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	if tb.tokens < float64(n) {
+		return false
+	}
+	tb.tokens -= float64(n)
+	return true
+}
+
+// Delay returns the time until a token is available.
+func (tb *TokenBucket) Delay() time.Duration {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	return tb.durationFor(1 - tb.tokens)
+}
+
+// Reserve takes n tokens out of the bucket and returns the delay after which the n events may happen.
+// The tokens are taken even if the bucket doesn't hold enough of them yet, so that following callers
+// queue up behind this reservation. An error is returned if n is not positive or exceeds the burst capacity.
+func (tb *TokenBucket) Reserve(n int) (time.Duration, error) {
+	if n <= 0 {
+		return 0, ErrInvalidTokens{n}
+	}
+	if n > tb.burst {
+		return 0, ErrBurstExceeded{N: n, Burst: tb.burst}
+	}
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	tb.tokens -= float64(n)
+	return tb.durationFor(-tb.tokens), nil
+}
+
+// cancel returns n reserved tokens to the bucket.
+func (tb *TokenBucket) cancel(n int) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	tb.tokens = math.Min(float64(tb.burst), tb.tokens+float64(n))
+}
+
+// Wait blocks until an event may happen or the given context is done.
+func (tb *TokenBucket) Wait(ctx context.Context) error {
+	return tb.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events may happen or the given context is done.
+// If the context is done before, the reserved tokens are returned to the bucket and the context's error is returned.
+func (tb *TokenBucket) WaitN(ctx context.Context, n int) error {
+	delay, err := tb.Reserve(n)
+	if err != nil {
+		return err
+	}
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-tb.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		tb.cancel(n)
+		return ctx.Err()
+	}
+}
+
+// Tokens returns the amount of currently available tokens.
+// The amount is negative if there are outstanding reservations.
+func (tb *TokenBucket) Tokens() float64 {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.refill(tb.clock.Now())
+	return tb.tokens
+}
+
+// Capacity returns the capacity of the bucket.
+func (tb *TokenBucket) Capacity() int {
+	return tb.burst
+}
+
+// Remaining returns the amount of whole tokens currently available.
+func (tb *TokenBucket) Remaining() int {
+	if tokens := tb.Tokens(); tokens > 0 {
+		return int(tokens)
+	}
+	return 0
+}
+
+// Rate returns the refill rate in tokens per second.
+func (tb *TokenBucket) Rate() float64 {
+	return tb.rate
+}
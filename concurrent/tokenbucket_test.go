@@ -0,0 +1,104 @@
+package concurrent
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllow(t *testing.T) {
+	clk := newFakeClock()
+	tb := NewTokenBucket(0.5, 3)
+	tb.SetClock(clk)
+
+	for i := 0; i < 3; i++ {
+		if !tb.Allow() {
+			t.Fatalf("event %d was not allowed within the burst", i)
+		}
+	}
+	if tb.Allow() {
+		t.Fatal("event was allowed with an empty bucket")
+	}
+	clk.Advance(time.Second)
+	if tb.Allow() {
+		t.Fatal("event was allowed with half a token in the bucket")
+	}
+	clk.Advance(time.Second)
+	if !tb.Allow() {
+		t.Fatal("event was not allowed after refilling a token")
+	}
+	clk.Advance(time.Hour)
+	if tb.Tokens() != 3 {
+		t.Errorf("tokens were %f, expected %d", tb.Tokens(), 3)
+	}
+}
+
+func TestTokenBucketReserve(t *testing.T) {
+	tb := NewTokenBucket(10, 5)
+	tb.SetClock(newFakeClock())
+
+	if delay, err := tb.Reserve(5); err != nil || delay != 0 {
+		t.Fatalf("delay was %v (%v), expected 0", delay, err)
+	}
+	if delay, _ := tb.Reserve(2); delay != 200*time.Millisecond {
+		t.Errorf("delay was %v, expected %v", delay, 200*time.Millisecond)
+	}
+	if delay, _ := tb.Reserve(1); delay != 300*time.Millisecond {
+		t.Errorf("delay was %v, expected %v", delay, 300*time.Millisecond)
+	}
+	for _, n := range []int{0, -3} {
+		if _, err := tb.Reserve(n); err != (ErrInvalidTokens{n}) {
+			t.Errorf("error was %v, expected ErrInvalidTokens for %d tokens", err, n)
+		}
+	}
+	if tb.AllowN(-3) {
+		t.Error("negative amount of events was allowed")
+	}
+	if _, err := tb.Reserve(6); err == nil {
+		t.Error("no error was returned for a reservation exceeding the burst")
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clk := newFakeClock()
+	s := clk.Now()
+	tb := NewTokenBucket(100, 1)
+	tb.SetClock(clk)
+	done := make(chan error)
+	go func() {
+		for i := 0; i < 6; i++ {
+			if err := tb.Wait(context.Background()); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	// the first event takes the token, every following one waits for a refill
+	for i := 0; i < 5; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(10 * time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := clk.Now().Sub(s); elapsed != 50*time.Millisecond {
+		t.Errorf("waiting for 6 events took %v, expected %v", elapsed, 50*time.Millisecond)
+	}
+
+	tb = NewTokenBucket(1, 1)
+	tb.SetClock(clk)
+	tb.Allow()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		done <- tb.Wait(ctx)
+	}()
+	clk.BlockUntil(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("error was %v, expected %v", err, context.Canceled)
+	}
+	if tb.Tokens() < 0 {
+		t.Errorf("tokens were %f, expected the cancelled reservation to be returned", tb.Tokens())
+	}
+}
@@ -0,0 +1,182 @@
+package concurrent
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// WindowCounter counts events over a sliding time window.
+// The window is divided into buckets of the given granularity, events
+// older than the window are dropped bucket by bucket.
+type WindowCounter struct {
+	mu      sync.Mutex
+	window  time.Duration
+	bucket  time.Duration
+	buckets []int64
+	// start of the newest bucket
+	head time.Time
+	// index of the newest bucket
+	idx   int
+	clock clock.Clock
+}
+
+// NewWindowCounter creates a new sliding window counter spanning the given window.
+// The window is divided into buckets of the given granularity.
+// This function panics if the bucket granularity is not positive or larger than the window.
+func NewWindowCounter(window time.Duration, bucket time.Duration) *WindowCounter {
+	if bucket <= 0 || bucket > window {
+		panic("bucket granularity must be positive and not larger than the window")
+	}
+	n := int(window / bucket)
+	if window%bucket != 0 {
+		n++
+	}
+	wc := &WindowCounter{window: time.Duration(n) * bucket, bucket: bucket, buckets: make([]int64, n)}
+	wc.SetClock(clock.Real)
+	return wc
+}
+
+// SetClock sets the clock used by the counter and drops all recorded events.
+func (wc *WindowCounter) SetClock(clk clock.Clock) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	wc.clock = clk
+	wc.head = clk.Now().Truncate(wc.bucket)
+	for i := range wc.buckets {
+		wc.buckets[i] = 0
+	}
+}
+
+// advance moves the head to the bucket of the given time, clearing all buckets which fell out of the window.
+func (wc *WindowCounter) advance(t time.Time) {
+	steps := int(t.Sub(wc.head) / wc.bucket)
+	if steps <= 0 {
+		return
+	}
+	if steps > len(wc.buckets) {
+		steps = len(wc.buckets)
+	}
+	for i := 0; i < steps; i++ {
+		wc.idx = (wc.idx + 1) % len(wc.buckets)
+		wc.buckets[wc.idx] = 0
+	}
+	wc.head = t.Truncate(wc.bucket)
+}
+
+// Incr records a single event.
+func (wc *WindowCounter) Incr() {
+	wc.Add(1)
+}
+
+// Add records n events.
+func (wc *WindowCounter) Add(n int64) {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	wc.advance(wc.clock.Now())
+	wc.buckets[wc.idx] += n
+}
+
+// Total returns the amount of events recorded within the window.
+func (wc *WindowCounter) Total() int64 {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	wc.advance(wc.clock.Now())
+	var sum int64
+	for _, v := range wc.buckets {
+		sum += v
+	}
+	return sum
+}
+
+// Rate returns the amount of events per second over the window.
+func (wc *WindowCounter) Rate() float64 {
+	return float64(wc.Total()) / wc.window.Seconds()
+}
+
+// Window returns the effective window of the counter, which is
+// the requested window rounded up to a multiple of the bucket granularity.
+func (wc *WindowCounter) Window() time.Duration {
+	return wc.window
+}
+
+// Reset drops all recorded events.
+func (wc *WindowCounter) Reset() {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+	for i := range wc.buckets {
+		wc.buckets[i] = 0
+	}
+}
+
+// EWMA computes an exponentially-weighted moving average of an event rate.
+// Older events decay continuously with the given time constant, so the rate
+// reflects roughly the events of the most recent time constant.
+type EWMA struct {
+	mu    sync.Mutex
+	tau   float64
+	rate  float64
+	last  time.Time
+	clock clock.Clock
+}
+
+// NewEWMA creates a new exponentially-weighted moving average with the given time constant.
+// This function panics if the time constant is not positive.
+func NewEWMA(tau time.Duration) *EWMA {
+	if tau <= 0 {
+		panic("time constant must be positive")
+	}
+	e := &EWMA{tau: tau.Seconds()}
+	e.SetClock(clock.Real)
+	return e
+}
+
+// SetClock sets the clock used by the moving average and resets it to zero.
+func (e *EWMA) SetClock(clk clock.Clock) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.clock = clk
+	e.rate = 0
+	e.last = clk.Now()
+}
+
+// decay decays the current rate up to the given time.
+func (e *EWMA) decay(t time.Time) {
+	dt := t.Sub(e.last).Seconds()
+	if dt <= 0 {
+		return
+	}
+	e.rate *= math.Exp(-dt / e.tau)
+	e.last = t
+}
+
+// Incr records a single event.
+func (e *EWMA) Incr() {
+	e.Add(1)
+}
+
+// Add records n events.
+func (e *EWMA) Add(n int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.decay(e.clock.Now())
+	e.rate += float64(n) / e.tau
+}
+
+// Rate returns the current moving average in events per second.
+func (e *EWMA) Rate() float64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.decay(e.clock.Now())
+	return e.rate
+}
+
+// Reset sets the moving average back to zero.
+func (e *EWMA) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rate = 0
+	e.last = e.clock.Now()
+}
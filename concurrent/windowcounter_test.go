@@ -0,0 +1,57 @@
+package concurrent
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowCounter(t *testing.T) {
+	clk := newFakeClock()
+	wc := NewWindowCounter(time.Minute, time.Second)
+	wc.SetClock(clk)
+
+	for i := 0; i < 60; i++ {
+		clk.Advance(time.Second)
+		wc.Add(2)
+	}
+	if wc.Total() != 120 {
+		t.Errorf("total was %d, expected %d", wc.Total(), 120)
+	}
+	if wc.Rate() != 2 {
+		t.Errorf("rate was %f, expected %f", wc.Rate(), 2.0)
+	}
+
+	// the oldest 30 buckets drop out of the window
+	clk.Advance(30 * time.Second)
+	if wc.Total() != 60 {
+		t.Errorf("total was %d, expected %d", wc.Total(), 60)
+	}
+
+	clk.Advance(time.Hour)
+	if wc.Total() != 0 {
+		t.Errorf("total was %d, expected %d", wc.Total(), 0)
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	clk := newFakeClock()
+	e := NewEWMA(time.Minute)
+	e.SetClock(clk)
+
+	// a steady 10 events per second converges towards a rate of 10
+	for i := 0; i < 600; i++ {
+		e.Add(10)
+		clk.Advance(time.Second)
+	}
+	if math.Abs(e.Rate()-10) > 0.5 {
+		t.Errorf("rate was %f, expected about %f", e.Rate(), 10.0)
+	}
+
+	// after one time constant without events the rate decays to 1/e
+	before := e.Rate()
+	clk.Advance(time.Minute)
+	if math.Abs(e.Rate()-before/math.E) > 0.01 {
+		t.Errorf("rate was %f, expected about %f", e.Rate(), before/math.E)
+	}
+}
@@ -1,72 +1,235 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 )
 
-// LoadJSONConfig loads the given JSON config into the given config struct.
+// ErrInvalidExtension describes a config file whose extension doesn't match the expected format.
+type ErrInvalidExtension struct {
+	Path     string
+	Ext      string
+	Expected string
+}
+
+func (e ErrInvalidExtension) Error() string {
+	return fmt.Sprintf("invalid file extension of %s: %s (expected %s)", e.Path, e.Ext, e.Expected)
+}
+
+// ErrReadConfig describes a config file which can't be read.
+type ErrReadConfig struct {
+	Path string
+	Err  error
+}
+
+func (e ErrReadConfig) Error() string {
+	return fmt.Sprintf("can't read config %s: %v", e.Path, e.Err)
+}
+
+func (e ErrReadConfig) Unwrap() error {
+	return e.Err
+}
+
+// ErrSyntax describes malformed content of a config file.
+type ErrSyntax struct {
+	Path   string
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e ErrSyntax) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s:%d:%d: %v", e.Path, e.Line, e.Column, e.Err)
+}
+
+func (e ErrSyntax) Unwrap() error {
+	return e.Err
+}
+
+// ErrType describes a value of a config file which doesn't fit the type of its field.
+type ErrType struct {
+	Path   string
+	Line   int
+	Column int
+	// Field is the path of the field, e.g. Pool.MaxOpen
+	Field string
+	Value string
+	Type  string
+}
+
+func (e ErrType) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: field %s expects %s but got %s", e.Path, e.Field, e.Type, e.Value)
+	}
+	return fmt.Sprintf("%s:%d:%d: field %s expects %s but got %s", e.Path, e.Line, e.Column, e.Field, e.Type, e.Value)
+}
+
+// ErrDecode describes an error of a config file's content which isn't tied to a position,
+// e.g. a value rejected by a field's UnmarshalText method.
+type ErrDecode struct {
+	Path string
+	Err  error
+}
+
+func (e ErrDecode) Error() string {
+	return fmt.Sprintf("can't decode config %s: %v", e.Path, e.Err)
+}
+
+func (e ErrDecode) Unwrap() error {
+	return e.Err
+}
+
+// position converts the given byte offset into a line and column, both starting at 1.
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// withPath sets the given path on positioned errors and wraps all other decoding errors.
+// Errors caused by the caller, e.g. passing a non-pointer, are returned as they are.
+func withPath(err error, configPath string) error {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case ErrSyntax:
+		e.Path = configPath
+		return e
+	case ErrType:
+		e.Path = configPath
+		return e
+	case *json.InvalidUnmarshalError:
+		return e
+	default:
+		return ErrDecode{Path: configPath, Err: err}
+	}
+}
+
+// LoadFile loads the given config file into the given config struct.
+// The format is picked by the file's extension.
 // config must be a pointer to a struct.
-func LoadJSONConfig(config interface{}, configPath string) {
+func LoadFile(config interface{}, configPath string) error {
+	if err := loadFile(config, configPath); err != nil {
+		return err
+	}
+	return resolveSecrets(config)
+}
+
+// loadFile is like LoadFile but doesn't resolve secrets.
+func loadFile(config interface{}, configPath string) error {
 	ext := path.Ext(configPath)
-	if ext != ".json" {
-		panic(fmt.Sprintf("invalid file extension: %s (expected .json)", ext))
+	name, has := FormatNameByExt(ext)
+	if !has {
+		return ErrInvalidExtension{configPath, ext, strings.Join(Extensions(), ", ")}
+	}
+	return decodeFile(config, configPath, name)
+}
+
+// LoadFileAs loads the given config file in the format with the given name into the given config struct,
+// regardless of its extension. Defaults (see SetDefaults) are applied before decoding,
+// secrets (see ResolveSecrets) are resolved afterwards.
+// config must be a pointer to a struct.
+func LoadFileAs(config interface{}, configPath string, formatName string) error {
+	if err := decodeFile(config, configPath, formatName); err != nil {
+		return err
 	}
-	bytes, err := ioutil.ReadFile(configPath)
+	return resolveSecrets(config)
+}
+
+// decodeFile applies the defaults and decodes the given config file in the given format into the given config.
+func decodeFile(config interface{}, configPath string, formatName string) error {
+	format, err := FormatByName(formatName)
 	if err != nil {
-		panic(err)
+		return err
+	}
+	if err := setDefaults(config); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return ErrReadConfig{configPath, err}
 	}
-	// this will panic if the given config is not a pointer
-	if err = json.Unmarshal(bytes, &config); err != nil {
+	return withPath(format.Unmarshal(data, config), configPath)
+}
+
+// LoadJSON loads the given JSON config into the given config struct.
+// config must be a pointer to a struct.
+func LoadJSON(config interface{}, configPath string) error {
+	ext := path.Ext(configPath)
+	if ext != ".json" {
+		return ErrInvalidExtension{configPath, ext, ".json"}
+	}
+	return LoadFileAs(config, configPath, "json")
+}
+
+// LoadJSONConfig loads the given JSON config into the given config struct.
+// config must be a pointer to a struct.
+// This function panics if the config can't be loaded, use LoadJSON to handle the error instead.
+func LoadJSONConfig(config interface{}, configPath string) {
+	if err := LoadJSON(config, configPath); err != nil {
 		panic(err)
 	}
 }
 
-// LoadFromPathOrEnvIfSet loads the given JSON from the given path or env variable (if set).
-// Optionally copies the config file from the given path to the env path.
-func LoadFromPathOrEnvIfSet(config interface{}, configPath string, envPath string, copySample bool) {
+// LoadFromPathOrEnv loads the given config from the given path or env variable (if set).
+// The format is picked by the file's extension.
+// Optionally copies the config file from the given path to the env path. If there is no file at the
+// given path either, a sample with all defaults (see WriteSample) is written to the env path instead.
+func LoadFromPathOrEnv(config interface{}, configPath string, envPath string, copySample bool) error {
 	configEnvPath := os.Getenv(envPath)
 	if len(configEnvPath) == 0 {
-		LoadJSONConfig(config, configPath)
-	} else {
-		if copySample {
-			// only copy sample config if it doesn't exist in the dest
-			if _, err := os.Stat(configEnvPath); err != nil {
-				if os.IsNotExist(err) {
-					// check if the sample config exists
-					if _, err2 := os.Stat(configPath); err2 != nil {
-						panic(err2)
-					}
-					if mvErr := MoveSampleConfig(configPath, configEnvPath); mvErr != nil {
-						panic(mvErr)
-					}
-				} else {
-					panic(err)
+		return LoadFile(config, configPath)
+	}
+	if copySample {
+		// only copy sample config if it doesn't exist in the dest
+		if _, err := os.Stat(configEnvPath); err != nil {
+			if !os.IsNotExist(err) {
+				return ErrReadConfig{configEnvPath, err}
+			}
+			// check if the sample config exists, otherwise generate it
+			if _, err2 := os.Stat(configPath); err2 != nil {
+				if !os.IsNotExist(err2) {
+					return ErrReadConfig{configPath, err2}
+				}
+				if err := WriteSample(config, configEnvPath); err != nil {
+					return err
 				}
+				return LoadFile(config, configEnvPath)
+			}
+			if mvErr := MoveSampleConfig(configPath, configEnvPath); mvErr != nil {
+				return mvErr
 			}
 		}
-		LoadJSONConfig(config, configEnvPath)
 	}
+	return LoadFile(config, configEnvPath)
 }
 
-// MoveSampleConfig copies the given sample config to the given destination path.
-func MoveSampleConfig(samplePath string, destPath string) error {
-	source, err := os.Open(samplePath)
-	if err != nil {
-		return err
-	}
-	defer source.Close()
-	dest, err := os.OpenFile(destPath, os.O_RDWR|os.O_CREATE, 0777)
-	if err != nil {
-		return err
-	}
-	defer dest.Close()
-	if _, err := io.Copy(dest, source); err != nil {
-		return err
+// LoadFromPathOrEnvIfSet loads the given config from the given path or env variable (if set).
+// Optionally copies the config file from the given path to the env path.
+// This function panics if the config can't be loaded, use LoadFromPathOrEnv to handle the error instead.
+func LoadFromPathOrEnvIfSet(config interface{}, configPath string, envPath string, copySample bool) {
+	if err := LoadFromPathOrEnv(config, configPath, envPath, copySample); err != nil {
+		panic(err)
 	}
-	return nil
+}
+
+// MoveSampleConfig atomically copies the given sample config to the given destination path,
+// replacing an existing file. See InstallSampleConfig for configuring permissions and merging.
+func MoveSampleConfig(samplePath string, destPath string) error {
+	return InstallSampleConfig(samplePath, destPath, InstallOptions{})
 }
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type pool struct {
+	MaxOpen int
+	MaxIdle int
+}
+
+type testconfig struct {
+	Name string
+	Port int
+	Pool pool
+}
+
+// writeFile writes the given content to a file with the given name in a temporary directory.
+func writeFile(t *testing.T, name string, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadJSON(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Name": "belt", "Port": 8080, "Pool": {"MaxOpen": 10}}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	c := testconfig{}
+	if err := LoadJSON(&c, p); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "belt" || c.Port != 8080 || c.Pool.MaxOpen != 10 {
+		t.Errorf("config was %+v", c)
+	}
+}
+
+func TestLoadJSONErrors(t *testing.T) {
+	c := testconfig{}
+	if err, ok := LoadJSON(&c, "config.yml").(ErrInvalidExtension); !ok || err.Ext != ".yml" {
+		t.Errorf("error was %v, expected an invalid extension error", err)
+	}
+	if err, ok := LoadJSON(&c, "missing.json").(ErrReadConfig); !ok || err.Path != "missing.json" {
+		t.Errorf("error was %v, expected a read error", err)
+	}
+
+	p := writeFile(t, "syntax.json", "{\n  \"Name\": \"belt\",\n  \"Port\": 80,,\n}")
+	defer os.RemoveAll(filepath.Dir(p))
+	syntaxErr, ok := LoadJSON(&c, p).(ErrSyntax)
+	if !ok || syntaxErr.Line != 3 || syntaxErr.Column != 14 {
+		t.Errorf("error was %v, expected a syntax error at 3:14", syntaxErr)
+	}
+
+	p = writeFile(t, "type.json", "{\n  \"Pool\": {\n    \"MaxOpen\": \"ten\"\n  }\n}")
+	defer os.RemoveAll(filepath.Dir(p))
+	typeErr, ok := LoadJSON(&c, p).(ErrType)
+	if !ok || typeErr.Line != 3 || typeErr.Column != 16 || typeErr.Field != "Pool.MaxOpen" || typeErr.Type != "int" {
+		t.Errorf("error was %v, expected a type error of Pool.MaxOpen at 3:16", typeErr)
+	}
+
+	p = writeFile(t, "number.json", "{\"Name\": 42, \"Pool\": []}")
+	defer os.RemoveAll(filepath.Dir(p))
+	typeErr, ok = LoadJSON(&c, p).(ErrType)
+	if !ok || typeErr.Line != 1 || typeErr.Column != 10 || typeErr.Field != "Name" {
+		t.Errorf("error was %v, expected a type error of Name at 1:10", typeErr)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const defaultTagKey = "default"
+
+// ErrDefault describes a default value which can't be assigned to its field.
+type ErrDefault struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e ErrDefault) Error() string {
+	return fmt.Sprintf("invalid default value %q for field %s: %v", e.Value, e.Field, e.Err)
+}
+
+func (e ErrDefault) Unwrap() error {
+	return e.Err
+}
+
+// SetDefaults sets the fields of the given config which still hold their zero value to the value
+// of their default tag, e.g. `default:"10"`. Slices are given as comma-separated elements, durations
+// in the format of time.ParseDuration. Nested structs are descended into, nil pointers to structs are
+// only allocated if any of their fields has a default.
+// The loaders apply the defaults before decoding, so values given in a config file take precedence.
+// config must be a pointer to a struct.
+func SetDefaults(config interface{}) error {
+	root, err := configValue(config)
+	if err != nil {
+		return err
+	}
+	_, err = walk(root, nil, func(field reflect.StructField, v reflect.Value, path []string) (bool, error) {
+		def, has := field.Tag.Lookup(defaultTagKey)
+		if !has || !isEmpty(v) {
+			return false, nil
+		}
+		if err := setValue(v, def); err != nil {
+			return false, ErrDefault{strings.Join(path, "."), def, err}
+		}
+		return true, nil
+	})
+	return err
+}
+
+// setDefaults applies the defaults if the given config is a pointer to a struct.
+func setDefaults(config interface{}) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return SetDefaults(config)
+}
+
+// WriteSample writes a JSON sample file of the given config type to the given path,
+// with every field set to its default (see SetDefaults). The file is written atomically
+// with DefaultFilePerm, existing files are replaced and missing parent directories created.
+// config must be a pointer to a struct, its values are not used.
+func WriteSample(config interface{}, samplePath string) error {
+	ext := path.Ext(samplePath)
+	if ext != ".json" {
+		return ErrInvalidExtension{samplePath, ext, ".json"}
+	}
+	root, err := configValue(config)
+	if err != nil {
+		return err
+	}
+	sample := reflect.New(root.Type()).Interface()
+	if err := SetDefaults(sample); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sample, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(samplePath), DefaultDirPerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(samplePath, append(data, '\n'), DefaultFilePerm)
+}
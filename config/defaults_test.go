@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type defaultedpool struct {
+	MaxOpen int `default:"10"`
+	MaxIdle int `default:"2"`
+}
+
+type defaultedconfig struct {
+	Name     string        `default:"belt"`
+	Timeout  time.Duration `default:"5s"`
+	Hosts    []string      `default:"a,b"`
+	Verbose  bool          `default:"true"`
+	Pool     defaultedpool
+	Fallback *defaultedpool
+	Other    *pool
+}
+
+func TestSetDefaults(t *testing.T) {
+	c := defaultedconfig{Name: "custom"}
+	if err := SetDefaults(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "custom" {
+		t.Errorf("name was %s, expected the preset value custom", c.Name)
+	}
+	if c.Timeout != 5*time.Second || !reflect.DeepEqual(c.Hosts, []string{"a", "b"}) || !c.Verbose {
+		t.Errorf("config was %+v", c)
+	}
+	if c.Pool.MaxOpen != 10 || c.Pool.MaxIdle != 2 {
+		t.Errorf("pool was %+v, expected defaults", c.Pool)
+	}
+	if c.Fallback == nil || c.Fallback.MaxOpen != 10 {
+		t.Errorf("fallback was %+v, expected allocated defaults", c.Fallback)
+	}
+	if c.Other != nil {
+		t.Errorf("other was %+v, expected nil", c.Other)
+	}
+
+	var invalid struct {
+		Port int `default:"http"`
+	}
+	if err, ok := SetDefaults(&invalid).(ErrDefault); !ok || err.Field != "Port" {
+		t.Errorf("error was %v, expected a default error for Port", err)
+	}
+}
+
+func TestLoadAppliesDefaults(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Pool": {"MaxOpen": 20}, "Verbose": false}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	c := defaultedconfig{}
+	if err := LoadFile(&c, p); err != nil {
+		t.Fatal(err)
+	}
+	if c.Pool.MaxOpen != 20 || c.Pool.MaxIdle != 2 {
+		t.Errorf("pool was %+v, expected MaxOpen from the file and MaxIdle from the defaults", c.Pool)
+	}
+	if c.Verbose {
+		t.Error("verbose was true, expected false from the file")
+	}
+
+	c = defaultedconfig{}
+	if err := Load(&c, Map(map[string]interface{}{"name": "layered"})); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "layered" || c.Timeout != 5*time.Second {
+		t.Errorf("config was %+v", c)
+	}
+}
+
+type defaultedrule struct {
+	Action string `default:"deny"`
+	Else   *defaultedrule
+}
+
+func TestLoadJSONRecursiveType(t *testing.T) {
+	p := writeFile(t, "rules.json", `{"Else": {"Else": {"Action": "allow"}}}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	done := make(chan error, 1)
+	c := defaultedrule{}
+	go func() {
+		done <- LoadJSON(&c, p)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loading a self-referential config didn't finish")
+	}
+	if c.Action != "deny" || c.Else == nil || c.Else.Else == nil || c.Else.Else.Action != "allow" {
+		t.Errorf("config was %+v", c)
+	}
+	// nil rules are not allocated to hold the defaults
+	if c.Else.Else.Else != nil {
+		t.Errorf("innermost rule was %+v, expected nil", c.Else.Else.Else)
+	}
+}
+
+func TestWriteSample(t *testing.T) {
+	p := writeFile(t, "sample.json", "")
+	defer os.RemoveAll(filepath.Dir(p))
+
+	if err := WriteSample(&defaultedconfig{Name: "ignored"}, p); err != nil {
+		t.Fatal(err)
+	}
+	c := defaultedconfig{}
+	if err := LoadJSON(&c, p); err != nil {
+		t.Fatal(err)
+	}
+	expected := defaultedconfig{}
+	SetDefaults(&expected)
+	if !reflect.DeepEqual(c, expected) {
+		t.Errorf("sample was %+v, expected %+v", c, expected)
+	}
+	if _, ok := WriteSample(&c, "sample.yml").(ErrInvalidExtension); !ok {
+		t.Error("expected an invalid extension error")
+	}
+}
+
+func TestLoadFromPathOrEnvGeneratesSample(t *testing.T) {
+	p := writeFile(t, "unused.json", "")
+	defer os.RemoveAll(filepath.Dir(p))
+	dest := filepath.Join(filepath.Dir(p), "config.json")
+	os.Setenv("BELT_TEST_SAMPLE_CONFIG", dest)
+	defer os.Unsetenv("BELT_TEST_SAMPLE_CONFIG")
+
+	c := defaultedconfig{}
+	if err := LoadFromPathOrEnv(&c, filepath.Join(filepath.Dir(p), "missing.json"), "BELT_TEST_SAMPLE_CONFIG", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("sample wasn't written: %v", err)
+	}
+	if c.Name != "belt" {
+		t.Errorf("name was %s, expected belt", c.Name)
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const envTagKey = "env"
+
+// ErrEnv describes an env variable whose value doesn't fit the type of its field.
+type ErrEnv struct {
+	Var   string
+	Field string
+	Value string
+	Err   error
+}
+
+func (e ErrEnv) Error() string {
+	return fmt.Sprintf("invalid value %q of env variable %s for field %s: %v", e.Value, e.Var, e.Field, e.Err)
+}
+
+func (e ErrEnv) Unwrap() error {
+	return e.Err
+}
+
+// EnvName returns the name of the env variable derived from the given field path,
+// e.g. APP_DB_MAX_OPEN for the prefix APP and the path DB.MaxOpen.
+func EnvName(prefix string, path []string) string {
+	parts := make([]string, 0, len(path)+1)
+	if len(prefix) > 0 {
+		parts = append(parts, prefix)
+	}
+	for _, name := range path {
+		parts = append(parts, snakeCase(name))
+	}
+	return strings.Join(parts, "_")
+}
+
+// ApplyEnv overrides the fields of the given config with the env variables named by their
+// env tags, e.g. `env:"DB_HOST"`. It is meant to be called after loading the config file.
+// Slices are given as comma-separated elements, durations in the format of time.ParseDuration.
+// config must be a pointer to a struct.
+func ApplyEnv(config interface{}) error {
+	return ApplyEnvPrefix(config, "")
+}
+
+// ApplyEnvPrefix is like ApplyEnv but additionally derives the env variable of fields without
+// an env tag from the given prefix and the field path (see EnvName), e.g. APP_DB_HOST for DB.Host.
+// If the prefix is empty, only env tags are used. Fields tagged with `env:"-"` are never overridden.
+func ApplyEnvPrefix(config interface{}, prefix string) error {
+	_, err := applyEnv(config, prefix)
+	return err
+}
+
+// applyEnv applies the env variables to the given config and returns the sources of the set fields.
+func applyEnv(config interface{}, prefix string) (Sources, error) {
+	root, err := configValue(config)
+	if err != nil {
+		return nil, err
+	}
+	sources := Sources{}
+	_, err = walk(root, nil, func(field reflect.StructField, v reflect.Value, path []string) (bool, error) {
+		name := field.Tag.Get(envTagKey)
+		if name == "-" {
+			return false, nil
+		}
+		if len(name) == 0 {
+			if len(prefix) == 0 {
+				return false, nil
+			}
+			name = EnvName(prefix, path)
+		}
+		value, has := os.LookupEnv(name)
+		if !has {
+			return false, nil
+		}
+		if err := setValue(v, value); err != nil {
+			return false, ErrEnv{name, strings.Join(path, "."), value, err}
+		}
+		sources[strings.Join(path, ".")] = "env:" + name
+		return true, nil
+	})
+	return sources, err
+}
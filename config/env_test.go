@@ -0,0 +1,115 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+type envconfig struct {
+	Name    string `env:"BELT_NAME"`
+	Debug   bool
+	Timeout time.Duration
+	Hosts   []string
+	Secret  string `env:"-"`
+	DB      struct {
+		Host string
+		Pool *pool
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"BELT_NAME":             "from tag",
+		"APP_DEBUG":             "true",
+		"APP_TIMEOUT":           "1m30s",
+		"APP_HOSTS":             "a, b,c",
+		"APP_SECRET":            "leaked",
+		"APP_DB_HOST":           "db.local",
+		"APP_DB_POOL_MAX_OPEN":  "10",
+		"APP_DB_POOL_UNRELATED": "1",
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	c := envconfig{Secret: "from file"}
+	if err := ApplyEnv(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "from tag" || c.Debug {
+		t.Errorf("only tagged fields should be set without a prefix, got %+v", c)
+	}
+
+	if err := ApplyEnvPrefix(&c, "APP"); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Debug || c.Timeout != 90*time.Second || c.DB.Host != "db.local" || c.Secret != "from file" {
+		t.Errorf("config was %+v", c)
+	}
+	if len(c.Hosts) != 3 || c.Hosts[1] != "b" {
+		t.Errorf("hosts were %v, expected [a b c]", c.Hosts)
+	}
+	if c.DB.Pool == nil || c.DB.Pool.MaxOpen != 10 {
+		t.Errorf("pool was %+v, expected MaxOpen to be 10", c.DB.Pool)
+	}
+
+	os.Setenv("APP_DEBUG", "maybe")
+	if err, ok := ApplyEnvPrefix(&c, "APP").(ErrEnv); !ok || err.Field != "Debug" {
+		t.Errorf("error was %v, expected an env error for Debug", err)
+	}
+}
+
+type ruleconfig struct {
+	Name string
+	Else *ruleconfig
+}
+
+func TestApplyEnvRecursiveType(t *testing.T) {
+	os.Setenv("APP_NAME", "outer")
+	os.Setenv("APP_ELSE_NAME", "inner")
+	defer os.Unsetenv("APP_NAME")
+	defer os.Unsetenv("APP_ELSE_NAME")
+
+	// nil pointers to a type on the path are skipped, set ones are descended into
+	c := ruleconfig{}
+	if err := ApplyEnvPrefix(&c, "APP"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "outer" || c.Else != nil {
+		t.Errorf("config was %+v", c)
+	}
+	c.Else = &ruleconfig{}
+	c.Else.Else = &c
+	if err := ApplyEnvPrefix(&c, "APP"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Else.Name != "inner" {
+		t.Errorf("name was %s, expected %s", c.Else.Name, "inner")
+	}
+}
+
+func TestApplyEnvInvalidConfig(t *testing.T) {
+	var nilConfig *envconfig
+	for _, config := range []interface{}{envconfig{}, nilConfig, new(int), nil} {
+		if _, ok := ApplyEnv(config).(ErrInvalidConfig); !ok {
+			t.Errorf("no ErrInvalidConfig was returned for %T", config)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	names := map[string][]string{
+		"APP_DB_HOST":        {"DB", "Host"},
+		"APP_POOL_MAX_OPEN":  {"Pool", "MaxOpen"},
+		"APP_HTTP_PORT":      {"HTTPPort"},
+		"APP_MONGO_DB_HOST":  {"MongoDB", "Host"},
+		"APP_KEEP_ALIVE_PW2": {"KeepAlivePW2"},
+	}
+	for expected, path := range names {
+		if name := EnvName("APP", path); name != expected {
+			t.Errorf("name was %s, expected %s", name, expected)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+const (
+	flagTagKey = "flag"
+	helpTagKey = "help"
+)
+
+// ErrDuplicateFlag is returned when the flag of a field is already defined on the flag set.
+type ErrDuplicateFlag struct {
+	Name  string
+	Field string
+}
+
+func (e ErrDuplicateFlag) Error() string {
+	return fmt.Sprintf("flag -%s of field %s is already defined", e.Name, e.Field)
+}
+
+// Sources maps the path of each field, e.g. DB.Host, to where its effective value came from:
+// "default", "file:<path>", "env:<variable>" or "flag:-<name>".
+type Sources map[string]string
+
+// flagvalue records the value of a flag bound to a config field until it is applied.
+type flagvalue struct {
+	config reflect.Value
+	path   []string
+	typ    reflect.Type
+	raw    string
+}
+
+func (fv *flagvalue) String() string {
+	if fv == nil || !fv.config.IsValid() {
+		return ""
+	}
+	if v := fieldByPath(fv.config, fv.path, false); v.IsValid() {
+		return format(v)
+	}
+	return ""
+}
+
+func (fv *flagvalue) Set(s string) error {
+	// validate the value right away so that flag parsing reports it
+	if err := setValue(reflect.New(fv.typ).Elem(), s); err != nil {
+		return err
+	}
+	fv.raw = s
+	return nil
+}
+
+func (fv *flagvalue) IsBoolFlag() bool {
+	return fv.typ.Kind() == reflect.Bool
+}
+
+// FlagBinding holds the flags generated for the fields of a config struct.
+type FlagBinding struct {
+	fs     *flag.FlagSet
+	values map[string]*flagvalue
+}
+
+// FlagName returns the name of the flag derived from the given field path,
+// e.g. db.pool.max-open for the path DB.Pool.MaxOpen.
+func FlagName(path []string) string {
+	parts := make([]string, len(path))
+	for i, name := range path {
+		parts[i] = strings.Replace(strings.ToLower(snakeCase(name)), "_", "-", -1)
+	}
+	return strings.Join(parts, ".")
+}
+
+// BindFlags defines a flag on the given flag set for every field of the given config.
+// Flag names are derived from the field paths (see FlagName) or given by a flag tag, the help
+// text is taken from the help tag. Fields tagged with `flag:"-"` get no flag.
+// The current values of the config are shown as the flags' defaults.
+// The parsed flags only take effect once Apply() is called, which allows loading
+// the config file and env variables in between.
+// An ErrDuplicateFlag is returned if a flag name is already defined, e.g. by another field.
+// config must be a pointer to a struct.
+func BindFlags(fs *flag.FlagSet, config interface{}) (*FlagBinding, error) {
+	root, err := configValue(config)
+	if err != nil {
+		return nil, err
+	}
+	b := &FlagBinding{fs: fs, values: make(map[string]*flagvalue)}
+	_, err = walk(root, nil, func(field reflect.StructField, v reflect.Value, path []string) (bool, error) {
+		name := field.Tag.Get(flagTagKey)
+		if name == "-" {
+			return false, nil
+		}
+		if len(name) == 0 {
+			name = FlagName(path)
+		}
+		if fs.Lookup(name) != nil {
+			return false, ErrDuplicateFlag{name, strings.Join(path, ".")}
+		}
+		fv := &flagvalue{config: root, path: path, typ: field.Type}
+		fs.Var(fv, name, field.Tag.Get(helpTagKey))
+		b.values[name] = fv
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Apply sets the fields of all flags which were given on the command line.
+// It returns the sources of the set fields.
+func (b *FlagBinding) Apply() (Sources, error) {
+	sources := Sources{}
+	var err error
+	b.fs.Visit(func(f *flag.Flag) {
+		fv, has := b.values[f.Name]
+		if !has || err != nil {
+			return
+		}
+		if err = setValue(fieldByPath(fv.config, fv.path, true), fv.raw); err != nil {
+			return
+		}
+		sources[strings.Join(fv.path, ".")] = "flag:-" + f.Name
+	})
+	return sources, err
+}
+
+// fileSources marks the fields which are present in the given decoded config file.
+func fileSources(t reflect.Type, tree map[string]interface{}, path []string, src string, sources Sources) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		var value interface{}
+		found := false
+		for k, v := range tree {
+			if keyMatches(field, k) {
+				value, found = v, true
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), field.Name)
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sub, ok := value.(map[string]interface{}); ok && !isLeaf(ft) {
+			fileSources(ft, sub, fieldPath, src, sources)
+			continue
+		}
+		sources[strings.Join(fieldPath, ".")] = src
+	}
+}
+
+// LoadWithOverrides loads the given config with increasing precedence from its current values and default tags,
+// the given config file, env variables (see ApplyEnvPrefix) and the flags of the given binding,
+// which must have been parsed already. An empty config path skips the file, a nil binding skips the flags.
+// Secrets (see ResolveSecrets) are resolved once all of them were applied. It returns the source of every field.
+// config must be a pointer to a struct.
+func LoadWithOverrides(config interface{}, configPath string, envPrefix string, flags *FlagBinding) (Sources, error) {
+	if err := SetDefaults(config); err != nil {
+		return nil, err
+	}
+	root, err := configValue(config)
+	if err != nil {
+		return nil, err
+	}
+	sources := Sources{}
+	walk(root, nil, func(field reflect.StructField, v reflect.Value, path []string) (bool, error) {
+		sources[strings.Join(path, ".")] = "default"
+		return false, nil
+	})
+
+	if len(configPath) > 0 {
+		tree := map[string]interface{}{}
+		if err := loadFile(&tree, configPath); err != nil {
+			return nil, err
+		}
+		if err := UnmarshalTree(tree, config); err != nil {
+			return nil, withPath(err, configPath)
+		}
+		fileSources(root.Type(), tree, nil, "file:"+configPath, sources)
+	}
+
+	envSources, err := applyEnv(config, envPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for path, src := range envSources {
+		sources[path] = src
+	}
+
+	if flags != nil {
+		flagSources, err := flags.Apply()
+		if err != nil {
+			return nil, err
+		}
+		for path, src := range flagSources {
+			sources[path] = src
+		}
+	}
+	if err := ResolveSecrets(config); err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// format formats the given field value for printing.
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
+
+// WriteEffective writes every field of the given config with its value and source to the given writer,
+// one field per line, e.g. "DB.Host = db.local (env:APP_DB_HOST)". Secrets are redacted (see Redact).
+// config must be a pointer to a struct.
+func WriteEffective(w io.Writer, config interface{}, sources Sources) error {
+	redacted, err := Redact(config)
+	if err != nil {
+		return err
+	}
+	_, err = walk(reflect.ValueOf(redacted).Elem(), nil, func(field reflect.StructField, v reflect.Value, path []string) (bool, error) {
+		p := strings.Join(path, ".")
+		src, has := sources[p]
+		if !has {
+			src = "default"
+		}
+		_, err := fmt.Fprintf(w, "%s = %s (%s)\n", p, format(v), src)
+		return false, err
+	})
+	return err
+}
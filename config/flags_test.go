@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type flagconfig struct {
+	Name  string `help:"name of the service"`
+	Port  int    `flag:"listen"`
+	Debug bool
+	Pool  *pool
+}
+
+func TestBindFlags(t *testing.T) {
+	c := flagconfig{Name: "belt", Port: 80}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	b, err := BindFlags(fs, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := fs.Lookup("name")
+	if f == nil || f.Usage != "name of the service" || f.DefValue != "belt" {
+		t.Fatalf("flag of Name was %+v", f)
+	}
+	if fs.Lookup("listen") == nil || fs.Lookup("pool.max-open") == nil {
+		t.Fatal("flags of Port and Pool.MaxOpen are missing")
+	}
+
+	if err := fs.Parse([]string{"-debug", "-pool.max-open", "10"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Debug {
+		t.Error("flag took effect before applying it")
+	}
+	if _, err := b.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Debug || c.Pool == nil || c.Pool.MaxOpen != 10 || c.Name != "belt" {
+		t.Errorf("config was %+v", c)
+	}
+
+	if err := fs.Parse([]string{"-listen", "http"}); err == nil {
+		t.Error("no error was returned for an invalid flag value")
+	}
+}
+
+func TestBindFlagsRecursiveType(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	if _, err := BindFlags(fs, &ruleconfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Lookup("name") == nil || fs.Lookup("else.name") != nil {
+		t.Error("only the flag of Name should be defined")
+	}
+	if _, err := BindFlags(fs, ruleconfig{}); err == nil {
+		t.Error("no error was returned for a config which is not a pointer")
+	}
+}
+
+func TestBindFlagsDuplicate(t *testing.T) {
+	var c struct {
+		Name  string
+		Alias string `flag:"name"`
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	_, err := BindFlags(fs, &c)
+	if e, ok := err.(ErrDuplicateFlag); !ok || e.Name != "name" || e.Field != "Alias" {
+		t.Errorf("error was %v, expected a duplicate flag error of Alias", err)
+	}
+
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Bool("name", false, "defined by the application")
+	if _, err := BindFlags(fs, &c); err == nil {
+		t.Error("no error was returned for a flag defined by the application")
+	}
+}
+
+func TestLoadWithOverrides(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Name": "file", "Port": 8080, "Debug": false}`)
+	defer os.RemoveAll(filepath.Dir(p))
+	os.Setenv("APP_PORT", "9090")
+	os.Setenv("APP_DEBUG", "false")
+	defer os.Unsetenv("APP_PORT")
+	defer os.Unsetenv("APP_DEBUG")
+
+	c := flagconfig{Name: "default"}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	b, err := BindFlags(fs, &c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-debug"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := LoadWithOverrides(&c, p, "APP", b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "file" || c.Port != 9090 || !c.Debug {
+		t.Errorf("config was %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEffective(&buf, &c, sources); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Name = file (file:" + p + ")",
+		"Port = 9090 (env:APP_PORT)",
+		"Debug = true (flag:-debug)",
+		"Pool.MaxOpen = 0 (default)",
+	}
+	for _, line := range expected {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("effective config is missing %q:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ErrUnknownFormat is returned for a format name which isn't registered.
+type ErrUnknownFormat struct {
+	Name string
+}
+
+func (e ErrUnknownFormat) Error() string {
+	return fmt.Sprintf("unknown config format: %s", e.Name)
+}
+
+// Format decodes the content of config files of a specific format.
+type Format interface {
+	// Unmarshal decodes data into v, which is a pointer to a struct or to a map[string]interface{}.
+	// Errors should be of type ErrSyntax or ErrType where possible.
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// FormatFunc adapts a function to the Format interface.
+type FormatFunc func(data []byte, v interface{}) error
+
+// Unmarshal calls f(data, v).
+func (f FormatFunc) Unmarshal(data []byte, v interface{}) error {
+	return f(data, v)
+}
+
+var (
+	formatsMu  sync.RWMutex
+	formats    = map[string]Format{}
+	extensions = map[string]string{}
+)
+
+func init() {
+	RegisterFormat("json", FormatFunc(unmarshalJSON), ".json")
+	RegisterFormat("yaml", FormatFunc(unmarshalYAML), ".yaml", ".yml")
+	RegisterFormat("toml", FormatFunc(unmarshalTOML), ".toml")
+	RegisterFormat("ini", FormatFunc(unmarshalINI), ".ini")
+}
+
+// RegisterFormat registers the given format under the given name and file extensions (including the dot).
+// Registering a name or extension again replaces the previous registration.
+// Registering a nil format removes the name and all of its extensions.
+func RegisterFormat(name string, format Format, exts ...string) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	if format == nil {
+		delete(formats, name)
+		for ext, n := range extensions {
+			if n == name {
+				delete(extensions, ext)
+			}
+		}
+		return
+	}
+	formats[name] = format
+	for _, ext := range exts {
+		extensions[strings.ToLower(ext)] = name
+	}
+}
+
+// FormatByName returns the format registered under the given name.
+func FormatByName(name string) (Format, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	format, has := formats[name]
+	if !has {
+		return nil, ErrUnknownFormat{name}
+	}
+	return format, nil
+}
+
+// FormatNameByExt returns the name of the format registered for the given file extension.
+func FormatNameByExt(ext string) (string, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	name, has := extensions[strings.ToLower(ext)]
+	return name, has
+}
+
+// Extensions returns the sorted file extensions of all registered formats.
+func Extensions() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	exts := []string{}
+	for ext := range extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// unmarshalJSON unmarshals the given JSON into v and annotates errors with their position.
+// Structs are decoded via a tree like the other formats, so that they accept the same values.
+func unmarshalJSON(data []byte, v interface{}) error {
+	var err error
+	if tree, ok := v.(*map[string]interface{}); ok {
+		err = unmarshalJSONTree(data, tree)
+	} else if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Struct {
+		tree := map[string]interface{}{}
+		if err = unmarshalJSONTree(data, &tree); err == nil && UnmarshalTree(tree, v) != nil {
+			// decode directly to report the error with its position
+			err = json.Unmarshal(data, v)
+		}
+	} else {
+		err = json.Unmarshal(data, v)
+	}
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *json.SyntaxError:
+		// the offset points behind the offending character
+		line, column := position(data, e.Offset-1)
+		return ErrSyntax{Line: line, Column: column, Err: e}
+	case *json.UnmarshalTypeError:
+		// the offset points behind the offending value
+		line, column := position(data, valueStart(data, e.Offset))
+		return ErrType{Line: line, Column: column, Field: e.Field, Value: e.Value, Type: e.Type.String()}
+	default:
+		return err
+	}
+}
+
+// unmarshalJSONTree decodes the given JSON object into a generic tree. Numbers are decoded as int64 or uint64
+// where possible instead of float64, so that large integers survive re-encoding the tree.
+func unmarshalJSONTree(data []byte, tree *map[string]interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(tree); err != nil {
+		// report errors, including their offsets, like json.Unmarshal does
+		return json.Unmarshal(data, tree)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		// trailing data after the object
+		return json.Unmarshal(data, tree)
+	}
+	*tree, _ = convertNumbers(*tree).(map[string]interface{})
+	return nil
+}
+
+// convertNumbers replaces the json.Numbers within the given value by int64s, or uint64s or float64s
+// if they don't fit.
+func convertNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = convertNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertNumbers(value)
+		}
+	}
+	return v
+}
+
+// valueStart returns the offset of the start of the JSON value which ends at the given offset.
+// For objects and arrays, the offset points right behind their opening bracket.
+func valueStart(data []byte, end int64) int64 {
+	if end > int64(len(data)) {
+		end = int64(len(data))
+	}
+	if end <= 0 {
+		return 0
+	}
+	i := end - 1
+	switch data[i] {
+	case '{', '[':
+		return i
+	case '"':
+		for i--; i >= 0; i-- {
+			if data[i] == '"' && !escaped(data, i) {
+				return i
+			}
+		}
+		return 0
+	}
+	// numbers and literals
+	for i > 0 && !strings.ContainsRune(",:[{ \t\r\n", rune(data[i-1])) {
+		i--
+	}
+	return i
+}
+
+// escaped reports whether the character at the given offset is escaped by an odd amount of backslashes.
+func escaped(data []byte, i int64) bool {
+	n := 0
+	for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// UnmarshalTree decodes a generic tree as produced by the formats into v.
+// Custom formats can use it to decode into structs once they parsed their content into a tree.
+// Structs are decoded using the same rules as JSON, so field names match case-insensitively
+// and json tags are honored regardless of the format. Keys may also be given by yaml and toml tags
+// and durations as strings in the format of time.ParseDuration, e.g. 5s.
+func UnmarshalTree(tree map[string]interface{}, v interface{}) error {
+	if m, ok := v.(*map[string]interface{}); ok {
+		*m = tree
+		return nil
+	}
+	data, err := json.Marshal(normalize(tree, reflect.TypeOf(v)))
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, v)
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		// positions within the intermediate JSON are meaningless
+		return ErrType{Field: e.Field, Value: e.Value, Type: e.Type.String()}
+	}
+	return err
+}
+
+// treeTagKeys are the tags which may name the tree key of a field besides its json tag.
+var treeTagKeys = []string{"yaml", "toml"}
+
+// tagName returns the name given by the tag with the given key, if any.
+func tagName(field reflect.StructField, key string) string {
+	name := strings.Split(field.Tag.Get(key), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// jsonName returns the key encoding/json decodes into the given field.
+func jsonName(field reflect.StructField) string {
+	if name := tagName(field, "json"); len(name) > 0 {
+		return name
+	}
+	return field.Name
+}
+
+// keyMatches reports whether the given tree key decodes into the given field. Keys match
+// the json name of the field or the name given by its yaml or toml tag case-insensitively.
+func keyMatches(field reflect.StructField, key string) bool {
+	if strings.EqualFold(jsonName(field), key) {
+		return true
+	}
+	for _, tagKey := range treeTagKeys {
+		if name := tagName(field, tagKey); len(name) > 0 && strings.EqualFold(name, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns a copy of the given tree value to be decoded into the given type by encoding/json.
+// Keys matching fields by their yaml or toml tag are renamed to the fields' json names
+// and duration strings are parsed.
+func normalize(value interface{}, t reflect.Type) interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			var elemType reflect.Type
+			switch {
+			case t == nil:
+			case t.Kind() == reflect.Struct:
+				if field, ok := fieldForKey(t, key); ok {
+					key, elemType = jsonName(field), field.Type
+				}
+			case t.Kind() == reflect.Map:
+				elemType = t.Elem()
+			}
+			normalized[key] = normalize(elem, elemType)
+		}
+		return normalized
+	case []interface{}:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		normalized := make([]interface{}, len(v))
+		for i, elem := range v {
+			normalized[i] = normalize(elem, elemType)
+		}
+		return normalized
+	case string:
+		if t == durationType {
+			if d, err := time.ParseDuration(v); err == nil {
+				return int64(d)
+			}
+		}
+	}
+	return value
+}
+
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+func unmarshalYAML(data []byte, v interface{}) error {
+	tree := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		line := 0
+		if match := yamlLine.FindStringSubmatch(err.Error()); match != nil {
+			line, _ = strconv.Atoi(match[1])
+		}
+		return ErrSyntax{Line: line, Err: err}
+	}
+	return UnmarshalTree(tree, v)
+}
+
+func unmarshalTOML(data []byte, v interface{}) error {
+	tree := map[string]interface{}{}
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		if e, ok := err.(toml.ParseError); ok {
+			return ErrSyntax{Line: e.Position.Line, Column: e.Position.Col, Err: err}
+		}
+		return ErrSyntax{Err: err}
+	}
+	return UnmarshalTree(tree, v)
+}
+
+// iniValue infers the type of an unquoted INI value.
+func iniValue(raw string) interface{} {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') && raw[len(raw)-1] == raw[0] {
+		return raw[1 : len(raw)-1]
+	}
+	if b, err := strconv.ParseBool(raw); err == nil {
+		return b
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		return f
+	}
+	return raw
+}
+
+// stripINIComment removes an inline comment, which starts with a ; or # preceded by whitespace,
+// from the given value. Comment characters within quoted values are kept.
+func stripINIComment(raw string) string {
+	start := 0
+	if len(raw) > 0 && (raw[0] == '"' || raw[0] == '\'') {
+		if end := strings.IndexByte(raw[1:], raw[0]); end >= 0 {
+			start = end + 2
+		}
+	}
+	for i := start; i < len(raw); i++ {
+		if (raw[i] == ';' || raw[i] == '#') && i > 0 && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+			return strings.TrimSpace(raw[:i])
+		}
+	}
+	return raw
+}
+
+// unmarshalINI decodes INI files. Sections become nested objects, dotted section names
+// like [DB.Pool] nest further. Unquoted values which look like booleans or numbers are
+// decoded as such, quoted values are always strings. Comments start with ; or #, inline comments
+// have to be preceded by whitespace.
+func unmarshalINI(data []byte, v interface{}) error {
+	tree := map[string]interface{}{}
+	section := tree
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0 || line[0] == ';' || line[0] == '#':
+			continue
+		case line[0] == '[':
+			if line[len(line)-1] != ']' {
+				return ErrSyntax{Line: lineNo, Column: len(line), Err: fmt.Errorf("unterminated section header")}
+			}
+			section = tree
+			for _, name := range strings.Split(line[1:len(line)-1], ".") {
+				name = strings.TrimSpace(name)
+				sub, ok := section[name].(map[string]interface{})
+				if !ok {
+					sub = map[string]interface{}{}
+					section[name] = sub
+				}
+				section = sub
+			}
+		default:
+			i := strings.IndexAny(line, "=:")
+			if i <= 0 {
+				return ErrSyntax{Line: lineNo, Column: 1, Err: fmt.Errorf("expected key = value")}
+			}
+			section[strings.TrimSpace(line[:i])] = iniValue(stripINIComment(strings.TrimSpace(line[i+1:])))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return UnmarshalTree(tree, v)
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileFormats(t *testing.T) {
+	files := map[string]string{
+		"config.yml":  "name: belt\nport: 8080\npool:\n  MaxOpen: 10\n",
+		"config.toml": "Name = \"belt\"\nPort = 8080\n\n[Pool]\nMaxOpen = 10\n",
+		"config.ini":  "Name = belt\nPort = 8080\n\n[Pool]\nMaxOpen = 10\n",
+	}
+	for name, content := range files {
+		p := writeFile(t, name, content)
+		defer os.RemoveAll(filepath.Dir(p))
+
+		c := testconfig{}
+		if err := LoadFile(&c, p); err != nil {
+			t.Fatal(err)
+		}
+		if c.Name != "belt" || c.Port != 8080 || c.Pool.MaxOpen != 10 {
+			t.Errorf("%s was loaded as %+v", name, c)
+		}
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	p := writeFile(t, "config.yml", "name: belt\npool:\n  MaxOpen: [\n")
+	defer os.RemoveAll(filepath.Dir(p))
+	c := testconfig{}
+	if err, ok := LoadFile(&c, p).(ErrSyntax); !ok || err.Path != p || err.Line == 0 {
+		t.Errorf("error was %v, expected a syntax error with a line", err)
+	}
+
+	p = writeFile(t, "config.toml", "Port = \"http\"\n")
+	defer os.RemoveAll(filepath.Dir(p))
+	if err, ok := LoadFile(&c, p).(ErrType); !ok || err.Field != "Port" {
+		t.Errorf("error was %v, expected a type error of Port", err)
+	}
+
+	if err, ok := LoadFile(&c, "config.xml").(ErrInvalidExtension); !ok || !strings.Contains(err.Expected, ".yaml") {
+		t.Errorf("error was %v, expected an invalid extension error", err)
+	}
+}
+
+func TestRegisterFormat(t *testing.T) {
+	RegisterFormat("kv", FormatFunc(func(data []byte, v interface{}) error {
+		tree := map[string]interface{}{}
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			kv := strings.SplitN(line, " ", 2)
+			tree[kv[0]] = kv[1]
+		}
+		return UnmarshalTree(tree, v)
+	}), ".kv")
+	defer RegisterFormat("kv", nil)
+
+	p := writeFile(t, "config.conf", "Name belt\n")
+	defer os.RemoveAll(filepath.Dir(p))
+	c := testconfig{}
+	if err := LoadFileAs(&c, p, "kv"); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "belt" {
+		t.Errorf("config was %+v", c)
+	}
+	if _, err := FormatByName("xml"); err == nil {
+		t.Error("no error was returned for an unknown format")
+	}
+
+	RegisterFormat("kv", nil)
+	if _, err := FormatByName("kv"); err == nil {
+		t.Error("no error was returned for an unregistered format")
+	}
+	if _, has := FormatNameByExt(".kv"); has {
+		t.Error("extension of an unregistered format was still registered")
+	}
+}
+
+type taggedconfig struct {
+	DBHost  string        `yaml:"db_host" toml:"db-host"`
+	Timeout time.Duration `json:"timeout"`
+	Retries []time.Duration
+	Comment string
+}
+
+func TestLoadFileFormatTags(t *testing.T) {
+	files := map[string]string{
+		"config.yml":  "db_host: db.local\ntimeout: 5s\nretries: [1s, 2s]\ncomment: \"a ; b\"\n",
+		"config.toml": "db-host = \"db.local\"\ntimeout = \"5s\"\nretries = [\"1s\", \"2s\"]\ncomment = \"a ; b\"\n",
+		"config.ini":  "DBHost = db.local ; inline comment\ntimeout = 5s # another one\nComment = \"a ; b\" ; quoted\n",
+		"config.json": `{"DBHost": "db.local", "timeout": "5s", "Retries": ["1s", 2000000000], "Comment": "a ; b"}`,
+	}
+	for name, content := range files {
+		p := writeFile(t, name, content)
+		defer os.RemoveAll(filepath.Dir(p))
+
+		c := taggedconfig{}
+		if err := LoadFile(&c, p); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if c.DBHost != "db.local" || c.Timeout != 5*time.Second || c.Comment != "a ; b" {
+			t.Errorf("%s was loaded as %+v", name, c)
+		}
+		if !strings.HasSuffix(name, ".ini") && (len(c.Retries) != 2 || c.Retries[1] != 2*time.Second) {
+			t.Errorf("retries of %s were %v, expected [1s 2s]", name, c.Retries)
+		}
+	}
+
+	p := writeFile(t, "invalid.yml", "timeout: soon\n")
+	defer os.RemoveAll(filepath.Dir(p))
+	if err, ok := LoadFile(&taggedconfig{}, p).(ErrType); !ok || err.Field != "timeout" {
+		t.Errorf("error was %v, expected a type error of timeout", err)
+	}
+}
+
+func TestLoadFileDecodeErrors(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Name": "belt"}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	// passing a non-pointer is the caller's fault, not the file's
+	err := LoadFileAs(testconfig{}, p, "json")
+	if _, ok := err.(*json.InvalidUnmarshalError); !ok {
+		t.Errorf("error was %#v, expected the unwrapped unmarshal error", err)
+	}
+
+	RegisterFormat("failing", FormatFunc(func(data []byte, v interface{}) error {
+		return errors.New("unsupported")
+	}))
+	defer RegisterFormat("failing", nil)
+	decodeErr, ok := LoadFileAs(&testconfig{}, p, "failing").(ErrDecode)
+	if !ok || decodeErr.Path != p {
+		t.Errorf("error was %v, expected a decode error of %s", decodeErr, p)
+	}
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultFilePerm is the permission of installed config files, which might contain secrets.
+	DefaultFilePerm os.FileMode = 0600
+	// DefaultDirPerm is the permission of parent directories created for installed config files.
+	DefaultDirPerm os.FileMode = 0755
+)
+
+// InstallOptions configures the installation of a sample config.
+type InstallOptions struct {
+	// Perm is the permission of the installed file, defaults to DefaultFilePerm.
+	Perm os.FileMode
+	// DirPerm is the permission of missing parent directories, defaults to DefaultDirPerm.
+	DirPerm os.FileMode
+	// Merge adds the keys of the sample which are missing in an existing destination file
+	// instead of replacing it, keeping all values and the permission of the existing file.
+	// The merged file is re-encoded, so formatting and comments are lost.
+	// Only JSON, YAML and TOML files can be merged.
+	Merge bool
+}
+
+// InstallSampleConfig atomically installs the given sample config at the given destination path:
+// the content is written to a temporary file next to the destination, synced and renamed over it,
+// so the destination never contains a half-written file. Missing parent directories are created.
+func InstallSampleConfig(samplePath string, destPath string, opts InstallOptions) error {
+	if opts.Perm == 0 {
+		opts.Perm = DefaultFilePerm
+	}
+	if opts.DirPerm == 0 {
+		opts.DirPerm = DefaultDirPerm
+	}
+	data, err := ioutil.ReadFile(samplePath)
+	if err != nil {
+		return ErrReadConfig{samplePath, err}
+	}
+	if opts.Merge {
+		if info, err := os.Stat(destPath); err == nil {
+			merged, changed, err := mergeMissingKeys(samplePath, destPath)
+			if err != nil || !changed {
+				return err
+			}
+			data = merged
+			opts.Perm = info.Mode().Perm()
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), opts.DirPerm); err != nil {
+		return err
+	}
+	return writeFileAtomic(destPath, data, opts.Perm)
+}
+
+// mergeMissingKeys returns the encoded destination config with the keys of the sample it misses
+// and whether any key was added.
+func mergeMissingKeys(samplePath string, destPath string) ([]byte, bool, error) {
+	sample := map[string]interface{}{}
+	if err := loadFile(&sample, samplePath); err != nil {
+		return nil, false, err
+	}
+	dest := map[string]interface{}{}
+	if err := loadFile(&dest, destPath); err != nil {
+		return nil, false, err
+	}
+	if !addMissing(dest, sample) {
+		return nil, false, nil
+	}
+	formatName, _ := FormatNameByExt(path.Ext(destPath))
+	data, err := encodeTree(dest, formatName)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// addMissing adds the keys of src which are missing in dst, descending into objects present in both.
+// Keys are matched case-insensitively. It reports whether any key was added.
+func addMissing(dst map[string]interface{}, src map[string]interface{}) bool {
+	added := false
+	for key, value := range src {
+		found := false
+		for k, existing := range dst {
+			if !strings.EqualFold(k, key) {
+				continue
+			}
+			found = true
+			dstObj, ok1 := existing.(map[string]interface{})
+			srcObj, ok2 := value.(map[string]interface{})
+			if ok1 && ok2 && addMissing(dstObj, srcObj) {
+				added = true
+			}
+			break
+		}
+		if !found {
+			dst[key] = value
+			added = true
+		}
+	}
+	return added
+}
+
+// encodeTree encodes the given tree in the format with the given name.
+func encodeTree(tree map[string]interface{}, formatName string) ([]byte, error) {
+	switch formatName {
+	case "json":
+		data, err := json.MarshalIndent(tree, "", "  ")
+		return append(data, '\n'), err
+	case "yaml":
+		return yaml.Marshal(tree)
+	case "toml":
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(tree)
+		return buf.Bytes(), err
+	default:
+		return nil, ErrUnknownFormat{formatName}
+	}
+}
+
+// writeFileAtomic writes the given data to a temporary file in the directory of the given path,
+// syncs it and renames it to the given path.
+func writeFileAtomic(dest string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(dest)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(dest)+".tmp")
+	if err != nil {
+		return err
+	}
+	// removing fails once the file was renamed
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return err
+	}
+	// persist the rename, not supported on all platforms
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInstallSampleConfig(t *testing.T) {
+	sample := writeFile(t, "sample.json", `{"Name": "sample", "Port": 80}`)
+	defer os.RemoveAll(filepath.Dir(sample))
+	dest := filepath.Join(filepath.Dir(sample), "nested", "dir", "config.json")
+
+	if err := InstallSampleConfig(sample, dest, InstallOptions{Perm: 0640}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("permission was %o, expected %o", info.Mode().Perm(), 0640)
+	}
+	entries, _ := ioutil.ReadDir(filepath.Dir(dest))
+	if len(entries) != 1 {
+		t.Errorf("directory contained %d files, expected no leftover temporary files", len(entries))
+	}
+
+	// a shorter sample must not leave parts of the previous content behind
+	if err := ioutil.WriteFile(sample, []byte(`{"Name": "x"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveSampleConfig(sample, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(dest)
+	if string(data) != `{"Name": "x"}` {
+		t.Errorf("content was %s", data)
+	}
+	if info, _ := os.Stat(dest); info.Mode().Perm() != DefaultFilePerm {
+		t.Errorf("permission was %o, expected %o", info.Mode().Perm(), DefaultFilePerm)
+	}
+}
+
+func TestInstallSampleConfigMerge(t *testing.T) {
+	sample := writeFile(t, "sample.json", `{"Name": "sample", "Port": 80, "Pool": {"MaxOpen": 10, "MaxIdle": 2}}`)
+	defer os.RemoveAll(filepath.Dir(sample))
+	dest := filepath.Join(filepath.Dir(sample), "config.json")
+	if err := ioutil.WriteFile(dest, []byte(`{"name": "user", "Pool": {"MaxOpen": 50}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InstallSampleConfig(sample, dest, InstallOptions{Merge: true}); err != nil {
+		t.Fatal(err)
+	}
+	c := testconfig{}
+	if err := LoadJSON(&c, dest); err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "user" || c.Port != 80 || c.Pool.MaxOpen != 50 || c.Pool.MaxIdle != 2 {
+		t.Errorf("merged config was %+v", c)
+	}
+
+	// nothing to add keeps the file as it is
+	before, _ := ioutil.ReadFile(dest)
+	if err := InstallSampleConfig(sample, dest, InstallOptions{Merge: true}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := ioutil.ReadFile(dest)
+	if string(before) != string(after) {
+		t.Errorf("config was rewritten:\n%s", after)
+	}
+}
+
+func TestInstallSampleConfigMergeKeepsValues(t *testing.T) {
+	sample := writeFile(t, "sample.json", `{"Name": "sample", "Port": 80}`)
+	defer os.RemoveAll(filepath.Dir(sample))
+	dest := filepath.Join(filepath.Dir(sample), "config.json")
+	// 2^53 + 1 can't be represented as a float64
+	if err := ioutil.WriteFile(dest, []byte(`{"ID": 9007199254740993, "Ratio": 0.5}`), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dest, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := InstallSampleConfig(sample, dest, InstallOptions{Merge: true}); err != nil {
+		t.Fatal(err)
+	}
+	var c struct {
+		ID    int64
+		Ratio float64
+		Port  int
+	}
+	if err := LoadJSON(&c, dest); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 9007199254740993 || c.Ratio != 0.5 || c.Port != 80 {
+		t.Errorf("merged config was %+v", c)
+	}
+	if info, _ := os.Stat(dest); info.Mode().Perm() != 0640 {
+		t.Errorf("permission was %o, expected %o", info.Mode().Perm(), 0640)
+	}
+}
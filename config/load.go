@@ -0,0 +1,194 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const mergeTagKey = "merge"
+
+// Source provides a part of a config as a generic tree.
+type Source interface {
+	Tree() (map[string]interface{}, error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func() (map[string]interface{}, error)
+
+// Tree calls f().
+func (f SourceFunc) Tree() (map[string]interface{}, error) {
+	return f()
+}
+
+// File returns a source reading the given config file. The format is picked by the file's extension.
+func File(path string) Source {
+	return SourceFunc(func() (map[string]interface{}, error) {
+		tree := map[string]interface{}{}
+		if err := LoadFile(&tree, path); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	})
+}
+
+// OptionalFile is like File but provides an empty tree if the file doesn't exist,
+// e.g. for a local override file.
+func OptionalFile(path string) Source {
+	return SourceFunc(func() (map[string]interface{}, error) {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return map[string]interface{}{}, nil
+		}
+		return File(path).Tree()
+	})
+}
+
+// Dir returns a source reading all config files of registered formats in the given directory
+// in lexical order, e.g. 00-base.yml before 10-db.yml. Subdirectories are ignored.
+func Dir(path string) Source {
+	return dirsource(path)
+}
+
+type dirsource string
+
+// files returns the sources of all config files within the directory.
+func (d dirsource) files() ([]Source, error) {
+	infos, err := ioutil.ReadDir(string(d))
+	if err != nil {
+		return nil, ErrReadConfig{string(d), err}
+	}
+	names := []string{}
+	for _, info := range infos {
+		if _, has := FormatNameByExt(filepath.Ext(info.Name())); has && !info.IsDir() {
+			names = append(names, info.Name())
+		}
+	}
+	sort.Strings(names)
+	sources := make([]Source, len(names))
+	for i, name := range names {
+		sources[i] = File(filepath.Join(string(d), name))
+	}
+	return sources, nil
+}
+
+func (d dirsource) Tree() (map[string]interface{}, error) {
+	return mergeSources(nil, []Source{d})
+}
+
+// Map returns a source providing the given in-memory tree, e.g. for values computed at runtime.
+func Map(tree map[string]interface{}) Source {
+	return SourceFunc(func() (map[string]interface{}, error) {
+		return tree, nil
+	})
+}
+
+// Load deep-merges the given sources in order into the given config, later sources taking precedence.
+// Objects are merged key by key, matching keys case-insensitively like JSON does.
+// Slices and all other values are replaced by later sources, unless the slice field
+// is tagged with `merge:"append"`, in which case the elements of later sources are appended.
+// Fields not present in any source keep their current values or are set to their defaults (see SetDefaults).
+// Secrets (see ResolveSecrets) are resolved after merging.
+// config must be a pointer to a struct.
+func Load(config interface{}, sources ...Source) error {
+	if err := SetDefaults(config); err != nil {
+		return err
+	}
+	root, err := configValue(config)
+	if err != nil {
+		return err
+	}
+	tree, err := mergeSources(root.Type(), sources)
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalTree(tree, config); err != nil {
+		return err
+	}
+	return ResolveSecrets(config)
+}
+
+// mergeSources merges the trees of the given sources in order. t is the struct type the trees describe, if known.
+func mergeSources(t reflect.Type, sources []Source) (map[string]interface{}, error) {
+	merged := map[string]interface{}{}
+	for _, source := range sources {
+		if d, ok := source.(dirsource); ok {
+			files, err := d.files()
+			if err != nil {
+				return nil, err
+			}
+			tree, err := mergeSources(t, files)
+			if err != nil {
+				return nil, err
+			}
+			merge(merged, tree, t)
+			continue
+		}
+		tree, err := source.Tree()
+		if err != nil {
+			return nil, err
+		}
+		merge(merged, tree, t)
+	}
+	return merged, nil
+}
+
+// fieldForKey returns the field of the given struct type which a tree key decodes into.
+func fieldForKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) == 0 && keyMatches(field, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// merge deep-merges src into dst. t is the struct type the trees describe, if known.
+func merge(dst map[string]interface{}, src map[string]interface{}, t reflect.Type) {
+	for key, value := range src {
+		// keys are matched case-insensitively, so the first spelling wins
+		dstKey := key
+		for k := range dst {
+			if strings.EqualFold(k, key) {
+				dstKey = k
+				break
+			}
+		}
+		field, known := fieldForKey(t, key)
+		var fieldType reflect.Type
+		if known {
+			fieldType = field.Type
+		} else if t != nil && t.Kind() == reflect.Map {
+			fieldType = t.Elem()
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if existing, ok := dst[dstKey].(map[string]interface{}); ok {
+				merge(existing, v, fieldType)
+				continue
+			}
+			copied := map[string]interface{}{}
+			merge(copied, v, fieldType)
+			dst[dstKey] = copied
+		case []interface{}:
+			existing, ok := dst[dstKey].([]interface{})
+			if ok && known && field.Tag.Get(mergeTagKey) == "append" {
+				dst[dstKey] = append(append([]interface{}{}, existing...), v...)
+				continue
+			}
+			dst[dstKey] = append([]interface{}{}, v...)
+		default:
+			dst[dstKey] = value
+		}
+	}
+}
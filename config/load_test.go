@@ -0,0 +1,88 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type layeredconfig struct {
+	Name    string
+	Hosts   []string
+	Plugins []string `merge:"append"`
+	DB      struct {
+		Host string
+		Port int
+	}
+	Labels map[string]string
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fragments := filepath.Join(dir, "conf.d")
+	os.Mkdir(fragments, 0755)
+
+	files := map[string]string{
+		"base.json":          `{"Name": "belt", "Hosts": ["a", "b"], "Plugins": ["log"], "DB": {"Host": "localhost", "Port": 3306}, "Labels": {"team": "core"}}`,
+		"production.yml":     "hosts: [c]\nplugins: [metrics]\ndb:\n  host: db.prod\nlabels:\n  env: prod\n",
+		"conf.d/10-db.toml":  "[DB]\nPort = 3307\n",
+		"conf.d/00-name.ini": "Name = first\n",
+		"conf.d/README":      "not a config",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := layeredconfig{}
+	err = Load(&c,
+		File(filepath.Join(dir, "base.json")),
+		File(filepath.Join(dir, "production.yml")),
+		Dir(fragments),
+		OptionalFile(filepath.Join(dir, "local.json")),
+		Map(map[string]interface{}{"name": "override"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "override" || c.DB.Host != "db.prod" || c.DB.Port != 3307 {
+		t.Errorf("config was %+v", c)
+	}
+	if len(c.Hosts) != 1 || c.Hosts[0] != "c" {
+		t.Errorf("hosts were %v, expected them to be replaced by [c]", c.Hosts)
+	}
+	if len(c.Plugins) != 2 || c.Plugins[1] != "metrics" {
+		t.Errorf("plugins were %v, expected them to be appended to [log metrics]", c.Plugins)
+	}
+	if c.Labels["team"] != "core" || c.Labels["env"] != "prod" {
+		t.Errorf("labels were %v, expected them to be merged", c.Labels)
+	}
+
+	if err := Load(&c, File(filepath.Join(dir, "missing.json"))); err == nil {
+		t.Error("no error was returned for a missing file")
+	}
+}
+
+func TestLoadLargeIntegers(t *testing.T) {
+	// 2^53 + 1 can't be represented as a float64
+	p := writeFile(t, "ids.json", `{"ID": 9007199254740993, "Max": 18446744073709551615, "Ratio": 0.1}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	var c struct {
+		ID    int64
+		Max   uint64
+		Ratio float64
+	}
+	if err := Load(&c, File(p), OptionalFile(p), Dir(filepath.Dir(p))); err != nil {
+		t.Fatal(err)
+	}
+	if c.ID != 9007199254740993 || c.Max != 18446744073709551615 || c.Ratio != 0.1 {
+		t.Errorf("config was %+v", c)
+	}
+}
@@ -0,0 +1,360 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	secretTagKey = "secret"
+	// encScheme is the scheme of values encrypted via Encrypt. It is reserved, so that an encrypted
+	// value is never used as is if no resolver was registered for it.
+	encScheme = "enc"
+	// Redacted replaces the values of secret fields when printing a config.
+	Redacted = "******"
+)
+
+// ErrSecret describes a secret which can't be resolved.
+type ErrSecret struct {
+	Field  string
+	Scheme string
+	Err    error
+}
+
+func (e ErrSecret) Error() string {
+	return fmt.Sprintf("can't resolve %s secret of field %s: %v", e.Scheme, e.Field, e.Err)
+}
+
+func (e ErrSecret) Unwrap() error {
+	return e.Err
+}
+
+// Resolver resolves references to secrets, e.g. the path of a file containing a password.
+type Resolver interface {
+	// Resolve returns the secret the given reference (without its scheme) points to.
+	Resolve(ref string) (string, error)
+}
+
+// ResolverFunc adapts a function to the Resolver interface.
+type ResolverFunc func(ref string) (string, error)
+
+// Resolve calls f(ref).
+func (f ResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Resolver{}
+)
+
+func init() {
+	RegisterResolver("file", ResolverFunc(resolveFile))
+	RegisterResolver("env", ResolverFunc(resolveEnv))
+}
+
+// RegisterResolver registers the given resolver for values of secret fields prefixed with the given scheme
+// and a colon, e.g. "vault" for "vault:db/password". Registering a scheme again replaces the previous resolver.
+// The schemes file and env are registered by default, enc must be registered with a key via NewAESResolver.
+func RegisterResolver(scheme string, r Resolver) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[scheme] = r
+}
+
+// resolverFor returns the resolver and reference of the given value, if its scheme is registered.
+func resolverFor(value string) (string, string, Resolver) {
+	i := strings.Index(value, ":")
+	if i < 0 {
+		return "", "", nil
+	}
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	return value[:i], value[i+1:], resolvers[value[:i]]
+}
+
+// resolveFile reads the secret from the given file, e.g. a Docker secret, without the trailing newline.
+func resolveFile(ref string) (string, error) {
+	data, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveEnv reads the secret from the given env variable, which must be set.
+func resolveEnv(ref string) (string, error) {
+	value, has := os.LookupEnv(ref)
+	if !has {
+		return "", fmt.Errorf("env variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// NewAESResolver creates a resolver decrypting secrets which were encrypted with the given key via Encrypt.
+// The key must be 16, 24 or 32 bytes long. Register it under the enc scheme:
+//
+//	RegisterResolver("enc", resolver)
+func NewAESResolver(key []byte) (Resolver, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return ResolverFunc(func(ref string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(ref)
+		if err != nil {
+			return "", err
+		}
+		if len(data) < gcm.NonceSize() {
+			return "", errors.New("ciphertext too short")
+		}
+		plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}), nil
+}
+
+// Encrypt encrypts the given secret with the given key using AES-GCM and returns
+// the value to put into the config file, e.g. "enc:3q2+7w...".
+func Encrypt(key []byte, secret string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	data := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return encScheme + ":" + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isSecret reports whether the given field is tagged with `secret:"true"`.
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get(secretTagKey) == "true"
+}
+
+// ResolveSecrets replaces the values of string and *string fields tagged with `secret:"true"` which are
+// prefixed with the scheme of a registered resolver, e.g. "file:/run/secrets/db_pw" or "env:DB_PW",
+// with the secret they refer to. Other values are kept as they are, except for values prefixed with
+// "enc:" while no resolver is registered for enc, which result in an ErrSecret. Structs nested in
+// pointers, interfaces, slices, arrays and maps are descended into as well.
+// The loaders resolve secrets once all sources were applied, so it only needs to be called
+// for configs which were populated otherwise.
+// config must be a pointer to a struct.
+func ResolveSecrets(config interface{}) error {
+	root, err := configValue(config)
+	if err != nil {
+		return err
+	}
+	_, err = walkSecrets(root, "", func(v reflect.Value, path string) (bool, error) {
+		if v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.String {
+			return false, nil
+		}
+		scheme, ref, resolver := resolverFor(v.String())
+		if resolver == nil {
+			if scheme == encScheme {
+				return false, ErrSecret{path, scheme, errors.New("no resolver is registered")}
+			}
+			return false, nil
+		}
+		secret, err := resolver.Resolve(ref)
+		if err != nil {
+			return false, ErrSecret{path, scheme, err}
+		}
+		v.SetString(secret)
+		return true, nil
+	}, map[uintptr]bool{})
+	return err
+}
+
+type secretFunc func(v reflect.Value, path string) (bool, error)
+
+// walkSecrets calls fn for all exported fields tagged with `secret:"true"` within the given value,
+// descending into structs, non-nil pointers and interfaces, slices, arrays and maps. Map values and
+// values within interfaces are copied, so they are only written back if fn reports that it changed them.
+// visited holds the pointers on the current path, which aren't descended into again.
+func walkSecrets(v reflect.Value, path string, fn secretFunc, visited map[uintptr]bool) (bool, error) {
+	set := false
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return false, nil
+		}
+		visited[v.Pointer()] = true
+		defer delete(visited, v.Pointer())
+		return walkSecrets(v.Elem(), path, fn, visited)
+	case reflect.Interface:
+		if v.IsNil() {
+			return false, nil
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		elemSet, err := walkSecrets(elem, path, fn, visited)
+		if elemSet {
+			v.Set(elem)
+		}
+		return elemSet, err
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if len(field.PkgPath) > 0 {
+				continue
+			}
+			fieldPath := joinPath(path, field.Name)
+			var fieldSet bool
+			var err error
+			if isSecret(field) {
+				fieldSet, err = fn(v.Field(i), fieldPath)
+			} else {
+				fieldSet, err = walkSecrets(v.Field(i), fieldPath, fn, visited)
+			}
+			if err != nil {
+				return set, err
+			}
+			set = set || fieldSet
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			elemSet, err := walkSecrets(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn, visited)
+			if err != nil {
+				return set, err
+			}
+			set = set || elemSet
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			elemSet, err := walkSecrets(elem, fmt.Sprintf("%s[%v]", path, key.Interface()), fn, visited)
+			if err != nil {
+				return set, err
+			}
+			if elemSet {
+				v.SetMapIndex(key, elem)
+			}
+			set = set || elemSet
+		}
+	}
+	return set, nil
+}
+
+// resolveSecrets resolves the secrets if the given config is a pointer to a struct.
+func resolveSecrets(config interface{}) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return ResolveSecrets(config)
+}
+
+// Redact returns a copy of the given config in which all non-empty fields tagged with `secret:"true"`
+// are replaced by Redacted (or their zero value if they are neither strings nor pointers to strings),
+// so that it can be printed or logged. The config is copied deeply, so secrets within nested structs,
+// pointers, interfaces, slices and maps are redacted as well.
+// config must be a pointer to a struct, the returned value is a pointer of the same type.
+func Redact(config interface{}) (interface{}, error) {
+	root, err := configValue(config)
+	if err != nil {
+		return nil, err
+	}
+	redacted := reflect.New(root.Type())
+	redacted.Elem().Set(copyValue(root, map[uintptr]reflect.Value{}))
+	walkSecrets(redacted.Elem(), "", func(v reflect.Value, path string) (bool, error) {
+		if isEmpty(v) {
+			return false, nil
+		}
+		switch {
+		case v.Kind() == reflect.String:
+			v.SetString(Redacted)
+		case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String:
+			redacted := reflect.New(v.Type().Elem())
+			redacted.Elem().SetString(Redacted)
+			v.Set(redacted)
+		default:
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return true, nil
+	}, map[uintptr]bool{})
+	return redacted.Interface(), nil
+}
+
+// copyValue returns a deep copy of the given value, copying structs, pointers, interfaces, slices, arrays and maps.
+// copies holds the copies of the pointers copied so far, so that shared and cyclic pointers are kept as such.
+func copyValue(v reflect.Value, copies map[uintptr]reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if len(v.Type().Field(i).PkgPath) > 0 {
+				continue
+			}
+			c.Field(i).Set(copyValue(v.Field(i), copies))
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			return c
+		}
+		if ptr, has := copies[v.Pointer()]; has {
+			c.Set(ptr)
+			return c
+		}
+		ptr := reflect.New(v.Type().Elem())
+		copies[v.Pointer()] = ptr
+		ptr.Elem().Set(copyValue(v.Elem(), copies))
+		c.Set(ptr)
+	case reflect.Interface:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(copyValue(v.Elem(), copies))
+	case reflect.Slice:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i), copies))
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(copyValue(v.Index(i), copies))
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return c
+		}
+		c.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		for _, key := range v.MapKeys() {
+			c.SetMapIndex(key, copyValue(v.MapIndex(key), copies))
+		}
+	default:
+		c.Set(v)
+	}
+	return c
+}
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretconfig struct {
+	User  string
+	PW    string `secret:"true"`
+	Token string `secret:"true"`
+	Key   string `secret:"true"`
+	Pool  *struct {
+		Name string `secret:"true"`
+	}
+}
+
+func TestResolveSecrets(t *testing.T) {
+	pwFile := writeFile(t, "db_pw", "s3cret\n")
+	defer os.RemoveAll(filepath.Dir(pwFile))
+	os.Setenv("BELT_TEST_TOKEN", "t0ken")
+	defer os.Unsetenv("BELT_TEST_TOKEN")
+
+	key := []byte("0123456789abcdef0123456789abcdef")
+	encrypted, err := Encrypt(key, "k3y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver, err := NewAESResolver(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RegisterResolver("enc", resolver)
+	defer RegisterResolver("enc", nil)
+
+	p := writeFile(t, "config.json", `{"User": "file:ignored", "PW": "file:`+pwFile+`", "Token": "env:BELT_TEST_TOKEN", "Key": "`+encrypted+`"}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	c := secretconfig{}
+	if err := LoadFile(&c, p); err != nil {
+		t.Fatal(err)
+	}
+	if c.User != "file:ignored" {
+		t.Errorf("user was %s, expected it not to be resolved", c.User)
+	}
+	if c.PW != "s3cret" || c.Token != "t0ken" || c.Key != "k3y" {
+		t.Errorf("config was %+v", c)
+	}
+
+	c = secretconfig{PW: "env:BELT_TEST_MISSING"}
+	if err, ok := ResolveSecrets(&c).(ErrSecret); !ok || err.Field != "PW" || err.Scheme != "env" {
+		t.Errorf("error was %v, expected a secret error for PW", err)
+	}
+
+	other, _ := NewAESResolver([]byte("fedcba9876543210"))
+	if _, err := other.Resolve(strings.TrimPrefix(encrypted, "enc:")); err == nil {
+		t.Error("expected decrypting with the wrong key to fail")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	c := secretconfig{User: "admin", PW: "s3cret"}
+	c.Pool = &struct {
+		Name string `secret:"true"`
+	}{"pool"}
+
+	v, err := Redact(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := v.(*secretconfig)
+	if redacted.User != "admin" || redacted.PW != Redacted || redacted.Token != "" || redacted.Pool.Name != Redacted {
+		t.Errorf("redacted config was %+v", redacted)
+	}
+	if c.PW != "s3cret" || c.Pool.Name != "pool" {
+		t.Errorf("config was modified: %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEffective(&buf, &c, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "s3cret") || !strings.Contains(buf.String(), "PW = "+Redacted+" (default)\n") {
+		t.Errorf("effective config wasn't redacted:\n%s", buf.String())
+	}
+}
+
+type upstream struct {
+	Host string
+	PW   string `secret:"true"`
+}
+
+type upstreamconfig struct {
+	Upstreams []upstream
+	Backups   map[string]*upstream
+}
+
+func TestSecretsInCollections(t *testing.T) {
+	os.Setenv("BELT_TEST_UPSTREAM_PW", "s3cret")
+	defer os.Unsetenv("BELT_TEST_UPSTREAM_PW")
+
+	c := upstreamconfig{
+		Upstreams: []upstream{{"a", "plain"}, {"b", "env:BELT_TEST_UPSTREAM_PW"}},
+		Backups:   map[string]*upstream{"c": {"c", "env:BELT_TEST_UPSTREAM_PW"}},
+	}
+	if err := ResolveSecrets(&c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Upstreams[0].PW != "plain" || c.Upstreams[1].PW != "s3cret" || c.Backups["c"].PW != "s3cret" {
+		t.Errorf("config was %+v", c)
+	}
+
+	v, err := Redact(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := v.(*upstreamconfig)
+	if redacted.Upstreams[1].PW != Redacted || redacted.Upstreams[1].Host != "b" || redacted.Backups["c"].PW != Redacted {
+		t.Errorf("redacted config was %+v", redacted)
+	}
+	if c.Upstreams[1].PW != "s3cret" || c.Backups["c"].PW != "s3cret" {
+		t.Errorf("config was modified: %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteEffective(&buf, &c, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "s3cret") {
+		t.Errorf("effective config wasn't redacted:\n%s", buf.String())
+	}
+
+	c.Upstreams[0].PW = "enc:c2VjcmV0"
+	if err, ok := ResolveSecrets(&c).(ErrSecret); !ok || err.Scheme != "enc" {
+		t.Errorf("error was %v, expected a secret error for the unregistered enc scheme", err)
+	}
+	c.Upstreams[0].PW = "env:BELT_TEST_MISSING"
+	if err, ok := ResolveSecrets(&c).(ErrSecret); !ok || err.Field != "Upstreams[0].PW" {
+		t.Errorf("error was %v, expected a secret error for Upstreams[0].PW", err)
+	}
+}
+
+type pointerconfig struct {
+	Token *string `secret:"true"`
+	Extra interface{}
+}
+
+func TestSecretsInPointersAndInterfaces(t *testing.T) {
+	os.Setenv("BELT_TEST_TOKEN", "t0ken")
+	defer os.Unsetenv("BELT_TEST_TOKEN")
+
+	token := "env:BELT_TEST_TOKEN"
+	c := pointerconfig{Token: &token, Extra: upstream{"a", "env:BELT_TEST_TOKEN"}}
+	if err := ResolveSecrets(&c); err != nil {
+		t.Fatal(err)
+	}
+	if *c.Token != "t0ken" || c.Extra.(upstream).PW != "t0ken" {
+		t.Errorf("config was %+v", c)
+	}
+
+	c.Extra = &upstream{"b", "s3cret"}
+	v, err := Redact(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted := v.(*pointerconfig)
+	if *redacted.Token != Redacted || redacted.Extra.(*upstream).PW != Redacted {
+		t.Errorf("redacted config was %+v", redacted)
+	}
+	if *c.Token != "t0ken" || c.Extra.(*upstream).PW != "s3cret" {
+		t.Errorf("config was modified: %+v", c)
+	}
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const validateTagKey = "validate"
+
+// Validator is implemented by config types which validate themselves beyond their validate tags.
+// Validate is called after the tags of all fields were checked.
+type Validator interface {
+	Validate() error
+}
+
+// Violation describes a single field which failed validation.
+type Violation struct {
+	// Field is the path of the field, e.g. DB.Pool.MaxOpen
+	Field   string
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	if len(v.Field) == 0 {
+		return v.Message
+	}
+	return fmt.Sprintf("%s: %s", v.Field, v.Message)
+}
+
+// ErrValidation holds all violations found while validating a config.
+type ErrValidation struct {
+	Violations []Violation
+}
+
+func (e ErrValidation) Error() string {
+	lines := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		lines[i] = v.String()
+	}
+	return fmt.Sprintf("invalid config: %s", strings.Join(lines, "; "))
+}
+
+// ErrRule describes a rule of a validate tag which can't be checked, e.g. an unknown rule or a malformed bound.
+type ErrRule struct {
+	Field string
+	Rule  string
+	Err   error
+}
+
+func (e ErrRule) Error() string {
+	return fmt.Sprintf("invalid validation rule %s of field %s: %v", e.Rule, e.Field, e.Err)
+}
+
+func (e ErrRule) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks the fields of the given config against their validate tags and calls the Validate()
+// method of the config and all nested structs implementing Validator.
+// All violations are aggregated into a single ErrValidation. Supported rules are:
+//
+//	required     the value must not be the zero value, slices and maps must not be empty
+//	min=N        numbers must be >= N, strings, slices and maps must have a length >= N
+//	max=N        numbers must be <= N, strings, slices and maps must have a length <= N
+//	oneof=a b c  the value must be one of the space-separated values
+//
+// Bounds of durations are given in the format of time.ParseDuration.
+// Structs nested in pointers, slices, arrays and maps are validated as well.
+// An ErrRule is returned if a tag contains a rule which can't be checked.
+// config must be a pointer to a struct.
+func Validate(config interface{}) error {
+	root, err := configValue(config)
+	if err != nil {
+		return err
+	}
+	val := &validation{visited: map[uintptr]bool{}}
+	if err := val.validateStruct(root, ""); err != nil {
+		return err
+	}
+	if len(val.violations) > 0 {
+		return ErrValidation{val.violations}
+	}
+	return nil
+}
+
+// validation holds the state of validating a config.
+type validation struct {
+	violations []Violation
+	// the pointers on the current path, which aren't descended into again
+	visited map[uintptr]bool
+}
+
+func joinPath(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// validateStruct validates the fields of the given struct and calls its Validate() method.
+func (val *validation) validateStruct(v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		fieldPath := joinPath(path, field.Name)
+		fv := v.Field(i)
+		if tag := field.Tag.Get(validateTagKey); len(tag) > 0 {
+			for _, rule := range strings.Split(tag, ",") {
+				rule = strings.TrimSpace(rule)
+				msg, err := check(fv, rule)
+				if err != nil {
+					return ErrRule{fieldPath, rule, err}
+				}
+				if len(msg) > 0 {
+					val.violations = append(val.violations, Violation{fieldPath, rule, msg})
+				}
+			}
+		}
+		if err := val.validateNested(fv, fieldPath); err != nil {
+			return err
+		}
+	}
+
+	var validator Validator
+	if v.CanAddr() {
+		validator, _ = v.Addr().Interface().(Validator)
+	} else {
+		validator, _ = v.Interface().(Validator)
+	}
+	if validator != nil {
+		if err := validator.Validate(); err != nil {
+			val.violations = append(val.violations, Violation{path, "Validate", err.Error()})
+		}
+	}
+	return nil
+}
+
+// validateNested descends into structs and structs within pointers, slices, arrays and maps.
+func (val *validation) validateNested(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || val.visited[v.Pointer()] {
+			return nil
+		}
+		val.visited[v.Pointer()] = true
+		defer delete(val.visited, v.Pointer())
+		return val.validateNested(v.Elem(), path)
+	case reflect.Struct:
+		if v.Type() != reflect.TypeOf(time.Time{}) {
+			return val.validateStruct(v, path)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := val.validateNested(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			if err := val.validateNested(v.MapIndex(key), fmt.Sprintf("%s[%v]", path, key.Interface())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// check checks the given value against the given rule and returns a message describing the violation.
+// An error is returned if the rule can't be checked.
+func check(v reflect.Value, rule string) (string, error) {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	switch name {
+	case "required":
+		if isEmpty(v) {
+			return "is required", nil
+		}
+	case "min", "max":
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return "", nil
+			}
+			v = v.Elem()
+		}
+		value, bound, err := compare(v, arg)
+		if err != nil {
+			return "", err
+		}
+		if name == "min" && value < bound {
+			return fmt.Sprintf("must be at least %s", arg), nil
+		}
+		if name == "max" && value > bound {
+			return fmt.Sprintf("must be at most %s", arg), nil
+		}
+	case "oneof":
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return "", nil
+			}
+			v = v.Elem()
+		}
+		s := fmt.Sprintf("%v", v.Interface())
+		for _, allowed := range strings.Fields(arg) {
+			if s == allowed {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(arg), ", ")), nil
+	default:
+		return "", errors.New("unknown rule")
+	}
+	return "", nil
+}
+
+// isEmpty reports whether the given value is its zero value or an empty slice or map.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// compare returns the value to compare of the given field and the parsed bound.
+func compare(v reflect.Value, arg string) (float64, float64, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		return float64(v.Int()), float64(d), err
+	}
+	bound, err := strconv.ParseFloat(arg, 64)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), bound, err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), bound, err
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), bound, err
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), bound, err
+	}
+	return 0, 0, fmt.Errorf("can't compare %s", v.Type())
+}
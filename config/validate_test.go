@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type validatedbackend struct {
+	Host string `validate:"required"`
+	Port int    `validate:"min=1,max=65535"`
+}
+
+type validatedconfig struct {
+	Name     string        `validate:"required,max=8"`
+	Mode     string        `validate:"oneof=dev prod"`
+	Timeout  time.Duration `validate:"min=1s"`
+	Tags     []string      `validate:"required"`
+	Backends []validatedbackend
+	Fallback *validatedbackend
+	MaxConns int
+	MinConns int
+}
+
+func (c *validatedconfig) Validate() error {
+	if c.MinConns > c.MaxConns {
+		return errors.New("MinConns must not exceed MaxConns")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	valid := validatedconfig{
+		Name: "api", Mode: "prod", Timeout: time.Second, Tags: []string{"a"},
+		Backends: []validatedbackend{{"localhost", 8080}}, MaxConns: 10,
+	}
+	if err := Validate(&valid); err != nil {
+		t.Fatalf("valid config failed validation: %v", err)
+	}
+
+	invalid := validatedconfig{
+		Name: "too-long-name", Mode: "test", Timeout: time.Millisecond,
+		Backends: []validatedbackend{{"localhost", 8080}, {"", 70000}},
+		Fallback: &validatedbackend{"fallback", 0},
+		MaxConns: 1, MinConns: 2,
+	}
+	err := Validate(&invalid)
+	validationErr, ok := err.(ErrValidation)
+	if !ok {
+		t.Fatalf("error was %v, expected ErrValidation", err)
+	}
+	expected := []string{
+		"Name: must be at most 8",
+		"Mode: must be one of dev, prod",
+		"Timeout: must be at least 1s",
+		"Tags: is required",
+		"Backends[1].Host: is required",
+		"Backends[1].Port: must be at most 65535",
+		"Fallback.Port: must be at least 1",
+		"MinConns must not exceed MaxConns",
+	}
+	if len(validationErr.Violations) != len(expected) {
+		t.Fatalf("violations were %v, expected %d", validationErr.Violations, len(expected))
+	}
+	for i, v := range validationErr.Violations {
+		if v.String() != expected[i] {
+			t.Errorf("violation was %q, expected %q", v.String(), expected[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid config: Name: must be at most 8; ") {
+		t.Errorf("error was %q", err.Error())
+	}
+}
+
+func TestValidateInvalidRules(t *testing.T) {
+	var unknown struct {
+		Name string `validate:"uppercase"`
+	}
+	var bound struct {
+		Port int `validate:"min=one"`
+	}
+	var unordered struct {
+		Debug bool `validate:"max=1"`
+	}
+	for _, c := range []interface{}{&unknown, &bound, &unordered} {
+		if _, ok := Validate(c).(ErrRule); !ok {
+			t.Errorf("no rule error was returned for %+v", c)
+		}
+	}
+}
+
+type validatedrule struct {
+	Name string `validate:"required"`
+	Else *validatedrule
+}
+
+func TestValidateNested(t *testing.T) {
+	c := validatedrule{Name: "outer", Else: &validatedrule{}}
+	c.Else.Else = &c
+	err, ok := Validate(&c).(ErrValidation)
+	if !ok || len(err.Violations) != 1 || err.Violations[0].Field != "Else.Name" {
+		t.Errorf("error was %v, expected a violation of Else.Name", err)
+	}
+
+	var m struct {
+		Rules map[string]validatedrule
+	}
+	m.Rules = map[string]validatedrule{"deny": {}}
+	err, ok = Validate(&m).(ErrValidation)
+	if !ok || len(err.Violations) != 1 || err.Violations[0].Field != "Rules[deny].Name" {
+		t.Errorf("error was %v, expected a violation of Rules[deny].Name", err)
+	}
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ErrInvalidConfig is returned when the given config isn't a non-nil pointer to a struct.
+type ErrInvalidConfig struct {
+	Type string
+}
+
+func (e ErrInvalidConfig) Error() string {
+	return fmt.Sprintf("config must be a non-nil pointer to a struct, got %s", e.Type)
+}
+
+// setValue parses the given string into the given value.
+// Slices are given as comma-separated elements.
+func setValue(v reflect.Value, s string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), 0, 0)
+		if len(strings.TrimSpace(s)) > 0 {
+			for _, part := range strings.Split(s, ",") {
+				ele := reflect.New(v.Type().Elem()).Elem()
+				if err := setValue(ele, strings.TrimSpace(part)); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, ele)
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// isLeaf reports whether the given type is set as a whole instead of walking into its fields.
+func isLeaf(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		return isLeaf(t.Elem())
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	return t.Kind() != reflect.Struct
+}
+
+// walkFunc is called for every leaf field with its value and field path. It reports whether it set the value.
+type walkFunc func(field reflect.StructField, v reflect.Value, path []string) (bool, error)
+
+// walk calls fn for all exported leaf fields of the given struct value, descending into nested structs.
+// Nil pointers to structs are only allocated if fn set any of their fields. Pointers leading back to
+// a struct type on the current path are not descended into if nil, as that would recurse forever.
+func walk(v reflect.Value, path []string, fn walkFunc) (bool, error) {
+	return walkStruct(v, path, fn, map[reflect.Type]bool{}, map[uintptr]bool{})
+}
+
+// walkStruct walks the given struct value, types and visited holding the struct types
+// and the addresses of the pointers on the current path.
+func walkStruct(v reflect.Value, path []string, fn walkFunc, types map[reflect.Type]bool, visited map[uintptr]bool) (bool, error) {
+	set := false
+	t := v.Type()
+	if !types[t] {
+		types[t] = true
+		defer delete(types, t)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+		fieldPath := append(append([]string{}, path...), field.Name)
+		fv := v.Field(i)
+		ft := field.Type
+		switch {
+		case isLeaf(ft):
+			fieldSet, err := fn(field, fv, fieldPath)
+			if err != nil {
+				return set, err
+			}
+			set = set || fieldSet
+		case ft.Kind() == reflect.Ptr:
+			target := fv
+			if fv.IsNil() {
+				if types[ft.Elem()] {
+					continue
+				}
+				target = reflect.New(ft.Elem())
+			} else {
+				if visited[fv.Pointer()] {
+					continue
+				}
+				visited[fv.Pointer()] = true
+			}
+			fieldSet, err := walkStruct(target.Elem(), fieldPath, fn, types, visited)
+			if !fv.IsNil() {
+				delete(visited, fv.Pointer())
+			}
+			if err != nil {
+				return set, err
+			}
+			if fieldSet && fv.IsNil() {
+				fv.Set(target)
+			}
+			set = set || fieldSet
+		default:
+			fieldSet, err := walkStruct(fv, fieldPath, fn, types, visited)
+			if err != nil {
+				return set, err
+			}
+			set = set || fieldSet
+		}
+	}
+	return set, nil
+}
+
+// fieldByPath returns the field of the given struct value at the given path.
+// Nil pointers to structs along the path are allocated if alloc is set,
+// otherwise an invalid value is returned when hitting one.
+func fieldByPath(v reflect.Value, path []string, alloc bool) reflect.Value {
+	for _, name := range path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.FieldByName(name)
+	}
+	return v
+}
+
+// configValue returns the struct the given config points to.
+// An ErrInvalidConfig is returned if config is not a non-nil pointer to a struct.
+func configValue(config interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrInvalidConfig{fmt.Sprintf("%T", config)}
+	}
+	return v.Elem(), nil
+}
+
+// snakeCase converts the given CamelCase name to upper snake case, e.g. MaxOpen to MAX_OPEN and HTTPPort to HTTP_PORT.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
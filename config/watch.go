@@ -0,0 +1,221 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+	"github.com/luca-moser/belt/debounce"
+)
+
+// WatchOptions configures a Watcher.
+type WatchOptions struct {
+	// Interval is the interval in which the files are checked for changes, defaults to one second.
+	Interval time.Duration
+	// Debounce delays the reload until the files didn't change for the given duration,
+	// so that a reload doesn't pick up a half-written file. Zero reloads right away.
+	Debounce time.Duration
+	// OnError is called with the error of a failed reload, e.g. an ErrSyntax or ErrValidation.
+	OnError func(err error)
+	// Clock defaults to the real clock.
+	Clock clock.Clock
+}
+
+// fileinfo is the state of a watched file used to detect changes.
+type fileinfo struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+type subscription struct {
+	f func(old interface{}, new interface{})
+}
+
+// Watcher reloads a config whenever one of its files changes.
+// A reloaded config is only swapped in if it decodes and validates (see Validate),
+// otherwise the previous config is kept.
+type Watcher struct {
+	mu        sync.Mutex
+	reloadMu  sync.Mutex
+	t         reflect.Type
+	paths     []string
+	opts      WatchOptions
+	current   atomic.Value
+	infos     []fileinfo
+	subs      []*subscription
+	debouncer *debounce.Debouncer
+	exit      chan struct{}
+	exited    bool
+}
+
+// Watch loads the given config from the given files like Load does, validates it and watches the files for changes.
+// Reloaded configs are decoded into a new value of the config's type, starting from its defaults (see SetDefaults).
+// The Watcher keeps a copy of the loaded config, so modifying config afterwards doesn't affect Config.
+// config must be a pointer to a struct.
+func Watch(config interface{}, opts WatchOptions, paths ...string) (*Watcher, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	root, err := configValue(config)
+	if err != nil {
+		return nil, err
+	}
+	w := &Watcher{
+		t: root.Type(), paths: paths, opts: opts,
+		exit: make(chan struct{}),
+	}
+	w.infos = w.stat()
+	if err := w.load(config); err != nil {
+		return nil, err
+	}
+	current := reflect.New(w.t)
+	current.Elem().Set(root)
+	w.current.Store(current.Interface())
+	if opts.Debounce > 0 {
+		w.debouncer = debounce.NewDebouncer(w.reload, opts.Debounce, debounce.Options{Clock: opts.Clock})
+	}
+	w.init()
+	return w, nil
+}
+
+// fires up a goroutine which polls the files for changes.
+func (w *Watcher) init() {
+	go func() {
+		for {
+			select {
+			case <-w.opts.Clock.After(w.opts.Interval):
+				if w.changed() && !w.trigger() {
+					return
+				}
+			case <-w.exit:
+				return
+			}
+		}
+	}()
+}
+
+// stat returns the current state of the watched files.
+func (w *Watcher) stat() []fileinfo {
+	infos := make([]fileinfo, len(w.paths))
+	for i, p := range w.paths {
+		if info, err := os.Stat(p); err == nil {
+			infos[i] = fileinfo{info.ModTime(), info.Size(), true}
+		}
+	}
+	return infos
+}
+
+// changed reports whether any of the files changed since the last check.
+func (w *Watcher) changed() bool {
+	infos := w.stat()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := !reflect.DeepEqual(infos, w.infos)
+	w.infos = infos
+	return changed
+}
+
+// trigger schedules a debounced reload or reloads right away.
+// It reports false without doing either if the watcher exited in the meantime.
+func (w *Watcher) trigger() bool {
+	w.mu.Lock()
+	if w.exited {
+		w.mu.Unlock()
+		return false
+	}
+	if w.debouncer != nil {
+		// scheduled under the lock, so that Exit cancels it
+		w.debouncer.Call()
+		w.mu.Unlock()
+		return true
+	}
+	w.mu.Unlock()
+	w.reload()
+	return true
+}
+
+// load loads and validates the files into the given config.
+func (w *Watcher) load(config interface{}) error {
+	sources := make([]Source, len(w.paths))
+	for i, p := range w.paths {
+		sources[i] = File(p)
+	}
+	if err := Load(config, sources...); err != nil {
+		return err
+	}
+	return Validate(config)
+}
+
+func (w *Watcher) reload() {
+	if err := w.Reload(); err != nil && w.opts.OnError != nil {
+		w.opts.OnError(err)
+	}
+}
+
+// Reload reloads the config right away, e.g. on SIGHUP. If the files can't be loaded or the
+// loaded config is invalid, the error is returned and the previous config is kept.
+// Subscribers are notified synchronously before Reload returns.
+func (w *Watcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	config := reflect.New(w.t).Interface()
+	if err := w.load(config); err != nil {
+		return err
+	}
+	old := w.current.Load()
+	w.current.Store(config)
+
+	w.mu.Lock()
+	subs := append([]*subscription{}, w.subs...)
+	w.mu.Unlock()
+	for _, sub := range subs {
+		sub.f(old, config)
+	}
+	return nil
+}
+
+// Config returns the current config, a pointer of the same type as the one passed to Watch.
+// The returned config must not be modified, as it is shared with all other callers.
+func (w *Watcher) Config() interface{} {
+	return w.current.Load()
+}
+
+// Subscribe registers the given function to be called with the old and new config after every successful reload.
+// The returned function removes the subscription.
+func (w *Watcher) Subscribe(f func(old interface{}, new interface{})) func() {
+	sub := &subscription{f}
+	w.mu.Lock()
+	w.subs = append(w.subs, sub)
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for i, s := range w.subs {
+			if s == sub {
+				w.subs = append(w.subs[:i], w.subs[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Exit stops watching the files. A pending debounced reload is canceled.
+func (w *Watcher) Exit() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.exited {
+		return
+	}
+	w.exited = true
+	close(w.exit)
+	if w.debouncer != nil {
+		w.debouncer.Cancel()
+	}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+type watchedconfig struct {
+	Name string `validate:"required"`
+	Port int    `default:"80" validate:"max=65535"`
+}
+
+func TestWatch(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Name": "belt"}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	clk := clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	errs := make(chan error, 1)
+	c := watchedconfig{}
+	w, err := Watch(&c, WatchOptions{
+		Interval: time.Second, Debounce: 500 * time.Millisecond, Clock: clk,
+		OnError: func(err error) { errs <- err },
+	}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Exit()
+	if current := w.Config().(*watchedconfig); current.Name != "belt" || current.Port != 80 {
+		t.Fatalf("config was %+v", current)
+	}
+
+	type change struct{ old, new *watchedconfig }
+	changes := make(chan change, 1)
+	w.Subscribe(func(old interface{}, new interface{}) {
+		changes <- change{old.(*watchedconfig), new.(*watchedconfig)}
+	})
+
+	// write changes the file, the poll picks it up and the debounce delays the reload
+	write := func(content string, modTime time.Time) {
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, modTime, modTime)
+		clk.BlockUntil(1)
+		clk.Advance(time.Second)
+		// the debounce timer and the next poll
+		clk.BlockUntil(2)
+		clk.Advance(500 * time.Millisecond)
+	}
+
+	write(`{"Name": "reloaded", "Port": 8080}`, time.Now().Add(time.Minute))
+	select {
+	case ch := <-changes:
+		if ch.old.Name != "belt" || ch.new.Name != "reloaded" || ch.new.Port != 8080 {
+			t.Errorf("change was %+v -> %+v", ch.old, ch.new)
+		}
+	default:
+		t.Fatal("subscriber wasn't notified")
+	}
+	if current := w.Config().(*watchedconfig); current.Name != "reloaded" {
+		t.Errorf("name was %s, expected reloaded", current.Name)
+	}
+
+	// an invalid config is rejected and the previous one kept
+	write(`{"Port": 70000}`, time.Now().Add(2*time.Minute))
+	select {
+	case err := <-errs:
+		var validationErr ErrValidation
+		if !errors.As(err, &validationErr) || len(validationErr.Violations) != 2 {
+			t.Errorf("error was %v, expected two violations", err)
+		}
+	default:
+		t.Fatal("error handler wasn't called")
+	}
+	select {
+	case ch := <-changes:
+		t.Errorf("subscriber was notified of invalid config %+v", ch.new)
+	default:
+	}
+	if current := w.Config().(*watchedconfig); current.Name != "reloaded" || current.Port != 8080 {
+		t.Errorf("config was %+v, expected the previous config", current)
+	}
+}
+
+func TestWatchExit(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Name": "belt"}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	clk := clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	c := watchedconfig{}
+	w, err := Watch(&c, WatchOptions{Interval: time.Second, Debounce: 500 * time.Millisecond, Clock: clk}, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Name = "modified"
+	if current := w.Config().(*watchedconfig); current == &c || current.Name != "belt" {
+		t.Errorf("config was %+v, expected a copy of the loaded config", current)
+	}
+
+	reloads := 0
+	w.Subscribe(func(old interface{}, new interface{}) {
+		reloads++
+	})
+	modTime := time.Now().Add(time.Minute)
+	if err := ioutil.WriteFile(p, []byte(`{"Name": "reloaded"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(p, modTime, modTime)
+	clk.BlockUntil(1)
+	clk.Advance(time.Second)
+	// the pending debounced reload is canceled
+	clk.BlockUntil(2)
+	w.Exit()
+	w.Exit()
+	clk.Advance(time.Second)
+	if reloads != 0 {
+		t.Errorf("config was reloaded %d times after exit, expected 0", reloads)
+	}
+}
+
+func TestWatchInvalidInitialConfig(t *testing.T) {
+	p := writeFile(t, "config.json", `{"Port": 8080}`)
+	defer os.RemoveAll(filepath.Dir(p))
+
+	if _, err := Watch(&watchedconfig{}, WatchOptions{}, p); err == nil {
+		t.Error("expected a validation error")
+	}
+}
@@ -13,22 +13,22 @@ import (
 type (
 	// SQLConfig defines a simple configuration for a SQL connection
 	SQLConfig struct {
-		Name      string
-		Host      string
+		Name      string `validate:"required"`
+		Host      string `default:"localhost" validate:"required"`
 		User      string
-		PW        string
-		Port      int
+		PW        string `secret:"true"`
+		Port      int    `validate:"min=1,max=65535"`
 		KeepAlive bool
 		Pool      PoolSettings
 	}
 
 	// PoolSettings defines the settings of a connection pool
 	PoolSettings struct {
-		MaxOpen     int
-		MaxIdle     int
-		MaxLifetime int
-		DialTimeout int
-		Timeout     int
+		MaxOpen     int `default:"10" validate:"min=0"`
+		MaxIdle     int `default:"2" validate:"min=0"`
+		MaxLifetime int `default:"300" validate:"min=0"`
+		DialTimeout int `default:"15" validate:"min=0"`
+		Timeout     int `default:"30" validate:"min=0"`
 	}
 
 	// MongoDBConfig defines a simple configuration for a MongoDB connection
@@ -36,12 +36,20 @@ type (
 		Host      string
 		Auth      bool
 		Username  string
-		Password  string
+		Password  string `secret:"true"`
 		Mechanism string
 		Source    string
 	}
 )
 
+// Validate checks that the pool doesn't keep more idle connections than it may open.
+func (p PoolSettings) Validate() error {
+	if p.MaxOpen > 0 && p.MaxIdle > p.MaxOpen {
+		return fmt.Errorf("MaxIdle (%d) must not exceed MaxOpen (%d)", p.MaxIdle, p.MaxOpen)
+	}
+	return nil
+}
+
 // GetMSSQLConnection creates a new MSSQL connection with the given config
 func GetMSSQLConnection(config SQLConfig) (*sql.DB, error) {
 	var keepAlive int
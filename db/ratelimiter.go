@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+	"github.com/luca-moser/belt/concurrent"
+)
+
+// SQLLimiter implements a fixed window rate limiter whose counters are kept in a shared SQL table,
+// so that all replicas of a service share the same limit. The table must have the following layout:
+//
+//	CREATE TABLE rate_limits (
+//		name         VARCHAR(255) NOT NULL,
+//		window_start BIGINT       NOT NULL,
+//		hits         BIGINT       NOT NULL,
+//		PRIMARY KEY (name, window_start)
+//	)
+//
+// Windows are derived from the local clock, therefore the clocks of all replicas should be synchronized.
+// If the store is unreachable, the limiter falls back to limiting locally and doesn't query the store
+// again until the backoff passed, so that an outage doesn't delay every event by the timeout.
+type SQLLimiter struct {
+	mu       sync.Mutex
+	conn     *sql.DB
+	table    string
+	name     string
+	rate     int
+	window   time.Duration
+	timeout  time.Duration
+	backoff  time.Duration
+	fallback concurrent.Limiter
+	clock    clock.Clock
+	// hits within the window which was last seen
+	lastWindow int64
+	lastHits   int64
+	// the store isn't queried before this time after it failed
+	downUntil time.Time
+}
+
+// tableName matches table names which may be put into queries, optionally qualified by a schema.
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ErrInvalidTable is returned when a table name isn't a plain, optionally schema-qualified identifier.
+type ErrInvalidTable struct {
+	Table string
+}
+
+func (e ErrInvalidTable) Error() string {
+	return fmt.Sprintf("invalid table name: %q", e.Table)
+}
+
+// NewSQLLimiter creates a new limiter which allows rate events per window for the given name.
+// The counters are stored in the given table of the given connection, e.g. one obtained via GetMySQLConnection.
+// The queries use ? placeholders, so the connection's driver must support them.
+// By default, a local sliding window counter with the same rate is used when the store is unreachable.
+// An error is returned if the table name isn't a plain identifier or the rate or window are not positive.
+func NewSQLLimiter(conn *sql.DB, table string, name string, rate int, window time.Duration) (*SQLLimiter, error) {
+	if !tableName.MatchString(table) {
+		return nil, ErrInvalidTable{table}
+	}
+	if rate <= 0 || window <= 0 {
+		return nil, fmt.Errorf("rate and window must be positive, got %d per %v", rate, window)
+	}
+	return &SQLLimiter{
+		conn: conn, table: table, name: name, rate: rate, window: window,
+		timeout: time.Second, backoff: 5 * time.Second,
+		fallback: concurrent.NewSlidingWindowCounter(rate, window),
+		clock:    clock.Real,
+	}, nil
+}
+
+// SetFallback sets the limiter used while the store is unreachable.
+func (l *SQLLimiter) SetFallback(fallback concurrent.Limiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fallback = fallback
+}
+
+// SetTimeout sets the maximum duration of a single round trip to the store.
+func (l *SQLLimiter) SetTimeout(timeout time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timeout = timeout
+}
+
+// SetBackoff sets the duration for which the store isn't queried after it failed, 5 seconds by default.
+func (l *SQLLimiter) SetBackoff(backoff time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.backoff = backoff
+}
+
+// SetClock sets the clock used to derive the windows.
+func (l *SQLLimiter) SetClock(clk clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = clk
+}
+
+// windowAt returns the start of the window containing the given time in nanoseconds since the epoch
+// and the remaining time until the window ends.
+func (l *SQLLimiter) windowAt(t time.Time) (int64, time.Duration) {
+	now := t.UnixNano()
+	start := now - now%int64(l.window)
+	return start, time.Duration(start + int64(l.window) - now)
+}
+
+// increment atomically increments the hits of the given window unless its limit was reached.
+// It returns the hits of the window and whether the event was counted.
+func (l *SQLLimiter) increment(ctx context.Context, window int64) (int64, bool, error) {
+	hits, counted, err := l.tryIncrement(ctx, window)
+	if err != nil {
+		// another replica might have inserted the row of the window concurrently
+		return l.tryIncrement(ctx, window)
+	}
+	return hits, counted, nil
+}
+
+func (l *SQLLimiter) tryIncrement(ctx context.Context, window int64) (int64, bool, error) {
+	tx, err := l.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	// the update locks the row until the transaction ends, so the following select
+	// reads the hits including our own increment. Denied events aren't counted,
+	// so that retrying clients don't keep their window full.
+	res, err := tx.ExecContext(ctx,
+		fmt.Sprintf("UPDATE %s SET hits = hits + 1 WHERE name = ? AND window_start = ? AND hits < ?", l.table),
+		l.name, window, l.rate)
+	if err != nil {
+		return 0, false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+
+	var hits int64
+	err = tx.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT hits FROM %s WHERE name = ? AND window_start = ?", l.table),
+		l.name, window).Scan(&hits)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.ExecContext(ctx,
+			fmt.Sprintf("INSERT INTO %s (name, window_start, hits) VALUES (?, ?, 1)", l.table),
+			l.name, window); err != nil {
+			return 0, false, err
+		}
+		return 1, true, tx.Commit()
+	case err != nil:
+		return 0, false, err
+	}
+	return hits, affected > 0, tx.Commit()
+}
+
+// Allow reports whether an event may happen now and records it in the store if so. It never blocks
+// longer than the configured timeout.
+func (l *SQLLimiter) Allow() bool {
+	l.mu.Lock()
+	now := l.clock.Now()
+	window, _ := l.windowAt(now)
+	timeout, fallback := l.timeout, l.fallback
+	down := now.Before(l.downUntil)
+	l.mu.Unlock()
+	if down {
+		return fallback.Allow()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	hits, counted, err := l.increment(ctx, window)
+	if err != nil {
+		l.mu.Lock()
+		l.downUntil = l.clock.Now().Add(l.backoff)
+		l.mu.Unlock()
+		return fallback.Allow()
+	}
+
+	l.mu.Lock()
+	if window >= l.lastWindow {
+		l.lastWindow, l.lastHits = window, hits
+	}
+	l.mu.Unlock()
+	return counted
+}
+
+// Delay returns the time until the current window ends if its limit was reached.
+// It relies on the hits seen by the last call to Allow() and doesn't query the store.
+func (l *SQLLimiter) Delay() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	window, remaining := l.windowAt(l.clock.Now())
+	if window != l.lastWindow || l.lastHits < int64(l.rate) {
+		return 0
+	}
+	return remaining
+}
+
+// Wait blocks until an event may happen or the given context is done.
+func (l *SQLLimiter) Wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if l.Allow() {
+			return nil
+		}
+		delay := l.Delay()
+		l.mu.Lock()
+		if delay == 0 {
+			delay = l.fallback.Delay()
+		}
+		clk := l.clock
+		l.mu.Unlock()
+		select {
+		case <-clk.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Cleanup removes the rows of all windows before the current one.
+func (l *SQLLimiter) Cleanup(ctx context.Context) error {
+	l.mu.Lock()
+	window, _ := l.windowAt(l.clock.Now())
+	l.mu.Unlock()
+	_, err := l.conn.ExecContext(ctx,
+		fmt.Sprintf("DELETE FROM %s WHERE name = ? AND window_start < ?", l.table),
+		l.name, window)
+	return err
+}
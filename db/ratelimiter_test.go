@@ -0,0 +1,337 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// fakestore is an in-memory rate limit table served by the fake SQL driver.
+type fakestore struct {
+	mu   sync.Mutex
+	hits map[string]int64
+	// down makes every statement fail as if the store was unreachable
+	down bool
+	// beforeInsert is called once before the next insert, e.g. to simulate a concurrent insert
+	beforeInsert func()
+	statements   int
+}
+
+var (
+	fakestoresMu sync.Mutex
+	fakestores   = map[string]*fakestore{}
+)
+
+func init() {
+	sql.Register("fakesql", fakedriver{})
+}
+
+// newFakeStore returns a connection to a new empty fake store.
+func newFakeStore(t *testing.T) (*sql.DB, *fakestore) {
+	store := &fakestore{hits: map[string]int64{}}
+	fakestoresMu.Lock()
+	fakestores[t.Name()] = store
+	fakestoresMu.Unlock()
+	conn, err := sql.Open("fakesql", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, store
+}
+
+func (s *fakestore) key(args []driver.Value) string {
+	return fmt.Sprintf("%v/%v", args[0], args[1])
+}
+
+func (s *fakestore) exec(query string, args []driver.Value) (int64, []int64, error) {
+	s.mu.Lock()
+	if s.down {
+		s.mu.Unlock()
+		return 0, nil, errors.New("connection refused")
+	}
+	s.statements++
+	insertHook := s.beforeInsert
+	if strings.HasPrefix(query, "INSERT") {
+		s.beforeInsert = nil
+	}
+	s.mu.Unlock()
+	if strings.HasPrefix(query, "INSERT") && insertHook != nil {
+		insertHook()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.Fields(query)[0] {
+	case "UPDATE":
+		hits, has := s.hits[s.key(args)]
+		if !has || hits >= args[2].(int64) {
+			return 0, nil, nil
+		}
+		s.hits[s.key(args)]++
+		return 1, nil, nil
+	case "INSERT":
+		if _, has := s.hits[s.key(args)]; has {
+			return 0, nil, errors.New("duplicate key")
+		}
+		s.hits[s.key(args)] = 1
+		return 1, nil, nil
+	case "SELECT":
+		hits, has := s.hits[s.key(args)]
+		if !has {
+			return 0, nil, nil
+		}
+		return 0, []int64{hits}, nil
+	case "DELETE":
+		var deleted int64
+		for key := range s.hits {
+			var name string
+			var window int64
+			fmt.Sscanf(strings.Replace(key, "/", " ", 1), "%s %d", &name, &window)
+			if name == args[0] && window < args[1].(int64) {
+				delete(s.hits, key)
+				deleted++
+			}
+		}
+		return deleted, nil, nil
+	}
+	return 0, nil, fmt.Errorf("unsupported query: %s", query)
+}
+
+type fakedriver struct{}
+
+func (fakedriver) Open(name string) (driver.Conn, error) {
+	fakestoresMu.Lock()
+	defer fakestoresMu.Unlock()
+	return &fakeconn{fakestores[name]}, nil
+}
+
+type fakeconn struct {
+	store *fakestore
+}
+
+func (c *fakeconn) Prepare(query string) (driver.Stmt, error) {
+	return &fakestmt{c.store, query}, nil
+}
+
+func (c *fakeconn) Close() error {
+	return nil
+}
+
+func (c *fakeconn) Begin() (driver.Tx, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if c.store.down {
+		return nil, errors.New("connection refused")
+	}
+	return c, nil
+}
+
+func (c *fakeconn) Commit() error {
+	return nil
+}
+
+func (c *fakeconn) Rollback() error {
+	return nil
+}
+
+type fakestmt struct {
+	store *fakestore
+	query string
+}
+
+func (s *fakestmt) Close() error {
+	return nil
+}
+
+func (s *fakestmt) NumInput() int {
+	return -1
+}
+
+func (s *fakestmt) Exec(args []driver.Value) (driver.Result, error) {
+	affected, _, err := s.store.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (s *fakestmt) Query(args []driver.Value) (driver.Rows, error) {
+	_, rows, err := s.store.exec(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakerows{rows}, nil
+}
+
+type fakerows struct {
+	values []int64
+}
+
+func (r *fakerows) Columns() []string {
+	return []string{"hits"}
+}
+
+func (r *fakerows) Close() error {
+	return nil
+}
+
+func (r *fakerows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func newFakeClock() *clock.Fake {
+	return clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestSQLLimiter(t *testing.T) {
+	conn, store := newFakeStore(t)
+	l, err := NewSQLLimiter(conn, "rate_limits", "api", 3, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk := newFakeClock()
+	l.SetClock(clk)
+
+	// the first event inserts the row of the window, the following ones update it
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("event %d was not allowed within the limit", i)
+		}
+	}
+	window := clk.Now().UnixNano()
+	if hits := store.hits[fmt.Sprintf("api/%d", window)]; hits != 3 {
+		t.Errorf("hits were %d, expected %d", hits, 3)
+	}
+	// denied events aren't counted
+	for i := 0; i < 2; i++ {
+		if l.Allow() {
+			t.Error("event was allowed after the limit was reached")
+		}
+	}
+	if hits := store.hits[fmt.Sprintf("api/%d", window)]; hits != 3 {
+		t.Errorf("hits were %d after denied events, expected %d", hits, 3)
+	}
+	if delay := l.Delay(); delay != time.Minute {
+		t.Errorf("delay was %v, expected %v", delay, time.Minute)
+	}
+
+	// the next window starts over
+	clk.Advance(time.Minute)
+	if !l.Allow() {
+		t.Error("event was not allowed in the next window")
+	}
+	if delay := l.Delay(); delay != 0 {
+		t.Errorf("delay was %v, expected 0", delay)
+	}
+
+	if err := l.Cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.hits) != 1 {
+		t.Errorf("rows were %d after the cleanup, expected %d", len(store.hits), 1)
+	}
+}
+
+func TestSQLLimiterConcurrentInsert(t *testing.T) {
+	conn, store := newFakeStore(t)
+	l, _ := NewSQLLimiter(conn, "rate_limits", "api", 3, time.Minute)
+	clk := newFakeClock()
+	l.SetClock(clk)
+
+	// another replica inserts the row between our update and insert
+	key := fmt.Sprintf("api/%d", clk.Now().UnixNano())
+	store.beforeInsert = func() {
+		store.mu.Lock()
+		store.hits[key] = 1
+		store.mu.Unlock()
+	}
+	if !l.Allow() {
+		t.Fatal("event was not allowed within the limit")
+	}
+	if store.hits[key] != 2 {
+		t.Errorf("hits were %d, expected the retry to count %d", store.hits[key], 2)
+	}
+}
+
+func TestSQLLimiterFallback(t *testing.T) {
+	conn, store := newFakeStore(t)
+	l, _ := NewSQLLimiter(conn, "rate_limits", "api", 2, time.Minute)
+	clk := newFakeClock()
+	l.SetClock(clk)
+	l.SetFallback(newFallback(1))
+	l.SetBackoff(10 * time.Second)
+
+	store.down = true
+	if !l.Allow() {
+		t.Fatal("event was not allowed by the fallback")
+	}
+	if l.Allow() {
+		t.Error("event was allowed after the fallback's limit was reached")
+	}
+
+	// the store isn't queried during the backoff
+	store.down = false
+	l.SetFallback(newFallback(1))
+	l.Allow()
+	if store.statements != 0 {
+		t.Errorf("statements were %d during the backoff, expected none", store.statements)
+	}
+
+	clk.Advance(10 * time.Second)
+	l.Allow()
+	if store.statements == 0 {
+		t.Error("store wasn't queried after the backoff")
+	}
+}
+
+// fallback allows a fixed amount of events.
+type fallback struct {
+	remaining int
+}
+
+func newFallback(n int) *fallback {
+	return &fallback{n}
+}
+
+func (f *fallback) Allow() bool {
+	if f.remaining == 0 {
+		return false
+	}
+	f.remaining--
+	return true
+}
+
+func (f *fallback) Delay() time.Duration {
+	return time.Second
+}
+
+func (f *fallback) Wait(ctx context.Context) error {
+	return nil
+}
+
+func TestNewSQLLimiterInvalidTable(t *testing.T) {
+	for _, table := range []string{"", "rate_limits; DROP TABLE users", "1limits", "a.b.c"} {
+		if _, err := NewSQLLimiter(nil, table, "api", 1, time.Second); err != (ErrInvalidTable{table}) {
+			t.Errorf("error was %v, expected ErrInvalidTable for %q", err, table)
+		}
+	}
+	if _, err := NewSQLLimiter(nil, "app.rate_limits", "api", 1, time.Second); err != nil {
+		t.Errorf("error was %v for a schema-qualified table", err)
+	}
+	if _, err := NewSQLLimiter(nil, "rate_limits", "api", 1, 0); err == nil {
+		t.Error("expected an error for a zero window")
+	}
+}
@@ -0,0 +1,138 @@
+package debounce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCanceled is returned to callers whose debounced call was canceled.
+var ErrCanceled = errors.New("debounced call was canceled")
+
+// CtxDebouncer debounces a function which takes a context and may fail.
+// The function executes with the debouncer's context; once that context is done,
+// pending executions are dropped. Every call can receive the result of the execution it was coalesced into.
+// The Leading edge behaves like Both.
+type CtxDebouncer struct {
+	d   *ArgDebouncer
+	ctx context.Context
+	f   func(ctx context.Context) error
+	mu  sync.Mutex
+	// stops watching the context once the current burst ended
+	unwatch chan struct{}
+}
+
+// NewCtxDebouncer creates a new context-aware debouncer which executes the given function
+// after calls stopped for the given debounce time.
+// Options.Coalesce is not used. As every call has to be coalesced into an execution,
+// the Leading edge behaves like Both.
+func NewCtxDebouncer(ctx context.Context, f func(ctx context.Context) error, wait time.Duration, opts Options) *CtxDebouncer {
+	if opts.Edge == Leading {
+		opts.Edge = Both
+	}
+	opts.Coalesce = func(acc interface{}, arg interface{}) interface{} {
+		if acc == nil {
+			return []chan error{arg.(chan error)}
+		}
+		return append(acc.([]chan error), arg.(chan error))
+	}
+	cd := &CtxDebouncer{ctx: ctx, f: f}
+	cd.d = NewArgDebouncer(cd.run, wait, opts)
+	cd.d.onIdle = cd.stopWatching
+	return cd
+}
+
+// watch drops the pending execution once the context is done, until the current burst ended.
+func (cd *CtxDebouncer) watch() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if cd.unwatch != nil || cd.ctx.Done() == nil {
+		return
+	}
+	unwatch := make(chan struct{})
+	cd.unwatch = unwatch
+	go func() {
+		select {
+		case <-cd.ctx.Done():
+			cd.drop(cd.ctx.Err())
+		case <-unwatch:
+		}
+	}()
+}
+
+// stopWatching stops watching the context.
+func (cd *CtxDebouncer) stopWatching() {
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+	if cd.unwatch != nil {
+		close(cd.unwatch)
+		cd.unwatch = nil
+	}
+}
+
+// deliver sends the given result to all callers.
+func deliver(callers []chan error, err error) {
+	for _, c := range callers {
+		c <- err
+	}
+}
+
+// run executes the function and delivers its result to all coalesced callers.
+func (cd *CtxDebouncer) run(arg interface{}) {
+	callers := arg.([]chan error)
+	if err := cd.ctx.Err(); err != nil {
+		deliver(callers, err)
+		return
+	}
+	deliver(callers, cd.f(cd.ctx))
+}
+
+// drop drops a pending execution and delivers the given error to its callers.
+func (cd *CtxDebouncer) drop(err error) {
+	if arg, pending := cd.d.cancel(); pending {
+		deliver(arg.([]chan error), err)
+	}
+}
+
+// Call schedules an execution of the function and returns a channel which receives
+// the result of the execution the call was coalesced into. The channel may be ignored.
+// On the leading edge, the function is executed synchronously by the calling goroutine.
+func (cd *CtxDebouncer) Call() <-chan error {
+	result := make(chan error, 1)
+	if err := cd.ctx.Err(); err != nil {
+		result <- err
+		return result
+	}
+	cd.watch()
+	cd.d.Call(result)
+	return result
+}
+
+// CallWait schedules an execution of the function and blocks until it finished or the given context is done.
+// It returns the result of the execution the call was coalesced into.
+// Giving up on waiting doesn't cancel the execution.
+func (cd *CtxDebouncer) CallWait(ctx context.Context) error {
+	select {
+	case err := <-cd.Call():
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush immediately executes a pending call and ends the current burst.
+// It reports whether a call was pending.
+func (cd *CtxDebouncer) Flush() bool {
+	return cd.d.Flush()
+}
+
+// Cancel drops a pending call and ends the current burst. Its callers receive ErrCanceled.
+func (cd *CtxDebouncer) Cancel() {
+	cd.drop(ErrCanceled)
+}
+
+// Pending reports whether a call is waiting to be executed.
+func (cd *CtxDebouncer) Pending() bool {
+	return cd.d.Pending()
+}
@@ -0,0 +1,94 @@
+package debounce
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCtxDebouncer(t *testing.T) {
+	clk := newFakeClock()
+	errSave := errors.New("disk full")
+	timesExecuted := 0
+	d := NewCtxDebouncer(context.Background(), func(ctx context.Context) error {
+		timesExecuted++
+		return errSave
+	}, 100*time.Millisecond, Options{Clock: clk})
+
+	results := []<-chan error{d.Call(), d.Call(), d.Call()}
+	clk.Advance(100 * time.Millisecond)
+	for _, r := range results {
+		if err := <-r; err != errSave {
+			t.Errorf("result was %v, expected %v", err, errSave)
+		}
+	}
+	if timesExecuted != 1 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 1)
+	}
+
+	r := d.Call()
+	d.Cancel()
+	if err := <-r; err != ErrCanceled {
+		t.Errorf("result was %v, expected %v", err, ErrCanceled)
+	}
+}
+
+func TestCtxDebouncerStopsWatching(t *testing.T) {
+	clk := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := NewCtxDebouncer(ctx, func(ctx context.Context) error {
+		return nil
+	}, 100*time.Millisecond, Options{Clock: clk})
+
+	watching := func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return d.unwatch != nil
+	}
+	if watching() {
+		t.Error("context was watched before any call")
+	}
+	for _, end := range []func(){
+		func() { clk.Advance(100 * time.Millisecond) },
+		func() { d.Flush() },
+		func() { d.Cancel() },
+	} {
+		r := d.Call()
+		if !watching() {
+			t.Error("context wasn't watched during the burst")
+		}
+		end()
+		<-r
+		if watching() {
+			t.Error("context was still watched after the burst ended")
+		}
+	}
+}
+
+func TestCtxDebouncerParentCanceled(t *testing.T) {
+	clk := newFakeClock()
+	ctx, cancel := context.WithCancel(context.Background())
+	d := NewCtxDebouncer(ctx, func(ctx context.Context) error {
+		t.Error("function executed after the context was canceled")
+		return nil
+	}, 100*time.Millisecond, Options{Clock: clk})
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	r := d.Call()
+	cancel()
+	select {
+	case err := <-r:
+		if err != context.Canceled {
+			t.Errorf("result was %v, expected %v", err, context.Canceled)
+		}
+	case <-waitCtx.Done():
+		t.Fatal("pending call wasn't dropped")
+	}
+	clk.Advance(time.Second)
+	if err := d.CallWait(waitCtx); err != context.Canceled {
+		t.Errorf("result was %v, expected %v", err, context.Canceled)
+	}
+}
@@ -3,12 +3,19 @@ package debounce
 import (
 	"sync"
 	"time"
+
+	"github.com/luca-moser/belt/clock"
 )
 
 // Debounce returns a debounced version of the given function.
 // A debounced function only executes if the function isn't called again
 // for the specified debounce time.
 func Debounce(f func(), debounce int) func() {
+	return DebounceWithClock(f, debounce, clock.Real)
+}
+
+// DebounceWithClock is like Debounce but measures the debounce time with the given clock.
+func DebounceWithClock(f func(), debounce int, clk clock.Clock) func() {
 	var mtx sync.Mutex
 	var callers int
 	return func() {
@@ -16,7 +23,7 @@ func Debounce(f func(), debounce int) func() {
 		callers++
 		mtx.Unlock()
 
-		<-time.After(time.Duration(debounce) * time.Millisecond)
+		<-clk.After(time.Duration(debounce) * time.Millisecond)
 
 		mtx.Lock()
 		callers--
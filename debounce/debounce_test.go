@@ -1,20 +1,35 @@
 package debounce
 
 import (
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/luca-moser/belt/clock"
 )
 
+func newFakeClock() *clock.Fake {
+	return clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
 func TestDebounce(t *testing.T) {
+	clk := newFakeClock()
 	timesExecuted := 0
-	debFunc := Debounce(func() {
+	debFunc := DebounceWithClock(func() {
 		timesExecuted++
-	}, 600)
+	}, 600, clk)
 
+	var wg sync.WaitGroup
 	for x := 0; x < 50; x++ {
-		go debFunc()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			debFunc()
+		}()
 	}
-	<-time.After(time.Duration(650) * time.Millisecond)
+	clk.BlockUntil(50)
+	clk.Advance(time.Duration(600) * time.Millisecond)
+	wg.Wait()
 
 	if timesExecuted != 1 {
 		t.Errorf("result was %d, expected %d", timesExecuted, 1)
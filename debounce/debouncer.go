@@ -0,0 +1,287 @@
+package debounce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// Edge defines on which edge of a burst of calls a debounced function executes.
+type Edge int
+
+const (
+	// Trailing executes the function once the calls stopped for the debounce time.
+	Trailing Edge = iota
+	// Leading executes the function on the first call of a burst and ignores the rest of the burst.
+	// A CtxDebouncer treats it like Both, as every call has to receive a result.
+	Leading
+	// Both executes the function on the first call of a burst and again
+	// once the calls stopped, if there were further calls during the burst.
+	Both
+)
+
+func (e Edge) leading() bool {
+	return e == Leading || e == Both
+}
+
+func (e Edge) trailing() bool {
+	return e == Trailing || e == Both
+}
+
+// Options defines the behavior of a Debouncer.
+type Options struct {
+	// Edge defines on which edge of a burst the function executes. Defaults to Trailing.
+	Edge Edge
+	// MaxWait guarantees that the function executes at least once per MaxWait
+	// while calls keep coming in. A zero value disables it.
+	MaxWait time.Duration
+	// Coalesce combines the arguments of all calls which are executed together.
+	// acc is nil for the first call. By default, the argument of the latest call is used.
+	// It is only used by argument-carrying debouncers.
+	Coalesce func(acc interface{}, arg interface{}) interface{}
+	// Clock is the clock used to measure time. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Debouncer executes a function once calls to it stopped for the debounce time.
+// Unlike Debounce, calls never block the calling goroutine.
+type Debouncer struct {
+	d *ArgDebouncer
+}
+
+// NewDebouncer creates a new debouncer which executes the given function after
+// calls stopped for the given debounce time.
+func NewDebouncer(f func(), wait time.Duration, opts Options) *Debouncer {
+	return &Debouncer{NewArgDebouncer(func(interface{}) { f() }, wait, opts)}
+}
+
+// Call schedules an execution of the function according to the debouncer's options.
+// On the leading edge, the function is executed synchronously by the calling goroutine.
+func (d *Debouncer) Call() {
+	d.d.Call(nil)
+}
+
+// Flush immediately executes a pending call and ends the current burst.
+// It reports whether a call was pending.
+func (d *Debouncer) Flush() bool {
+	return d.d.Flush()
+}
+
+// Cancel drops a pending call and ends the current burst.
+func (d *Debouncer) Cancel() {
+	d.d.Cancel()
+}
+
+// Pending reports whether a call is waiting to be executed.
+func (d *Debouncer) Pending() bool {
+	return d.d.Pending()
+}
+
+// ArgDebouncer is a Debouncer whose calls carry an argument.
+// The function receives the argument of the latest call or the arguments of all calls
+// since the last execution combined by Options.Coalesce.
+type ArgDebouncer struct {
+	mu   sync.Mutex
+	f    func(arg interface{})
+	wait time.Duration
+	opts Options
+	// whether a burst of calls is ongoing
+	active bool
+	// whether the function has to execute on the trailing edge
+	pending  bool
+	arg      interface{}
+	timer    clock.Timer
+	maxTimer clock.Timer
+	// invalidate timers which fired while they were replaced or the burst ended
+	gen   int
+	burst int
+	// called after a burst ended
+	onIdle func()
+}
+
+// NewArgDebouncer creates a new argument-carrying debouncer which executes the given function
+// after calls stopped for the given debounce time.
+func NewArgDebouncer(f func(arg interface{}), wait time.Duration, opts Options) *ArgDebouncer {
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &ArgDebouncer{f: f, wait: wait, opts: opts}
+}
+
+// coalesce adds the given argument to the arguments of the pending call. The debouncer must be locked.
+func (d *ArgDebouncer) coalesce(arg interface{}) {
+	var acc interface{}
+	if d.pending {
+		acc = d.arg
+	}
+	if d.opts.Coalesce != nil {
+		d.arg = d.opts.Coalesce(acc, arg)
+	} else {
+		d.arg = arg
+	}
+	d.pending = true
+}
+
+// take returns the argument of the pending call and clears it. The debouncer must be locked.
+func (d *ArgDebouncer) take() interface{} {
+	arg := d.arg
+	d.arg = nil
+	d.pending = false
+	return arg
+}
+
+// Call schedules an execution of the function with the given argument according to the debouncer's options.
+// On the leading edge, the function is executed synchronously by the calling goroutine.
+func (d *ArgDebouncer) Call(arg interface{}) {
+	d.mu.Lock()
+	invoke := false
+	if !d.active {
+		d.active = true
+		if d.opts.Edge.leading() {
+			invoke = true
+			if d.opts.Coalesce != nil {
+				arg = d.opts.Coalesce(nil, arg)
+			}
+		} else {
+			d.coalesce(arg)
+		}
+		if d.opts.MaxWait > 0 {
+			d.startMaxTimer()
+		}
+	} else if d.opts.Edge.trailing() {
+		d.coalesce(arg)
+	}
+	d.startTimer()
+	d.mu.Unlock()
+
+	if invoke {
+		d.f(arg)
+	}
+}
+
+// startTimer (re)starts the debounce timer. The debouncer must be locked.
+func (d *ArgDebouncer) startTimer() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.gen++
+	gen := d.gen
+	d.timer = d.opts.Clock.AfterFunc(d.wait, func() {
+		d.expire(gen)
+	})
+}
+
+// startMaxTimer starts the max wait timer. The debouncer must be locked.
+func (d *ArgDebouncer) startMaxTimer() {
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+	}
+	burst := d.burst
+	d.maxTimer = d.opts.Clock.AfterFunc(d.opts.MaxWait, func() {
+		d.expireMax(burst)
+	})
+}
+
+// stop stops all timers and ends the current burst. The debouncer must be locked.
+func (d *ArgDebouncer) stop() {
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if d.maxTimer != nil {
+		d.maxTimer.Stop()
+	}
+	d.gen++
+	d.burst++
+	d.active = false
+}
+
+// finish executes the function if a call was pending and notifies that the burst ended.
+func (d *ArgDebouncer) finish(invoke bool, arg interface{}) {
+	if invoke {
+		d.f(arg)
+	}
+	if d.onIdle != nil {
+		d.onIdle()
+	}
+}
+
+// expire ends the burst after the calls stopped for the debounce time.
+func (d *ArgDebouncer) expire(gen int) {
+	d.mu.Lock()
+	if gen != d.gen {
+		d.mu.Unlock()
+		return
+	}
+	invoke := d.pending
+	arg := d.take()
+	d.stop()
+	d.mu.Unlock()
+	d.finish(invoke, arg)
+}
+
+// expireMax executes a pending call as the burst lasted for the max wait time.
+func (d *ArgDebouncer) expireMax(burst int) {
+	d.mu.Lock()
+	if !d.active || burst != d.burst {
+		d.mu.Unlock()
+		return
+	}
+	if !d.opts.Edge.trailing() {
+		// let the next call start a new burst and execute on its leading edge
+		d.stop()
+		d.mu.Unlock()
+		d.finish(false, nil)
+		return
+	}
+	invoke := d.pending
+	arg := d.take()
+	d.startMaxTimer()
+	d.mu.Unlock()
+
+	if invoke {
+		d.f(arg)
+	}
+}
+
+// Flush immediately executes a pending call and ends the current burst.
+// It reports whether a call was pending.
+func (d *ArgDebouncer) Flush() bool {
+	d.mu.Lock()
+	invoke := d.pending
+	arg := d.take()
+	d.stop()
+	d.mu.Unlock()
+	d.finish(invoke, arg)
+	return invoke
+}
+
+// Cancel drops a pending call and ends the current burst.
+func (d *ArgDebouncer) Cancel() {
+	d.cancel()
+}
+
+// cancel drops a pending call, ends the current burst and returns the argument of the dropped call.
+func (d *ArgDebouncer) cancel() (interface{}, bool) {
+	d.mu.Lock()
+	pending := d.pending
+	arg := d.take()
+	d.stop()
+	d.mu.Unlock()
+	d.finish(false, nil)
+	return arg, pending
+}
+
+// Pending reports whether a call is waiting to be executed.
+func (d *ArgDebouncer) Pending() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
+
+// idle reports whether no burst is ongoing.
+func (d *ArgDebouncer) idle() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return !d.active
+}
@@ -0,0 +1,98 @@
+package debounce
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebouncerTrailing(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	d := NewDebouncer(func() { timesExecuted++ }, 100*time.Millisecond, Options{Clock: clk})
+
+	for x := 0; x < 5; x++ {
+		d.Call()
+		clk.Advance(50 * time.Millisecond)
+	}
+	if timesExecuted != 0 {
+		t.Fatalf("result was %d, expected %d", timesExecuted, 0)
+	}
+	clk.Advance(50 * time.Millisecond)
+	if timesExecuted != 1 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 1)
+	}
+}
+
+func TestDebouncerLeading(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	d := NewDebouncer(func() { timesExecuted++ }, 100*time.Millisecond, Options{Edge: Leading, Clock: clk})
+
+	for x := 0; x < 5; x++ {
+		d.Call()
+		clk.Advance(50 * time.Millisecond)
+	}
+	if timesExecuted != 1 {
+		t.Fatalf("result was %d, expected %d", timesExecuted, 1)
+	}
+	clk.Advance(time.Second)
+	d.Call()
+	if timesExecuted != 2 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 2)
+	}
+}
+
+func TestDebouncerBoth(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	d := NewDebouncer(func() { timesExecuted++ }, 100*time.Millisecond, Options{Edge: Both, Clock: clk})
+
+	// a single call only executes on the leading edge
+	d.Call()
+	clk.Advance(time.Second)
+	if timesExecuted != 1 {
+		t.Fatalf("result was %d, expected %d", timesExecuted, 1)
+	}
+	d.Call()
+	d.Call()
+	clk.Advance(time.Second)
+	if timesExecuted != 3 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 3)
+	}
+}
+
+func TestDebouncerMaxWait(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	d := NewDebouncer(func() { timesExecuted++ }, 100*time.Millisecond, Options{MaxWait: 300 * time.Millisecond, Clock: clk})
+
+	// calls every 50ms never let the debounce time pass
+	for x := 0; x < 20; x++ {
+		d.Call()
+		clk.Advance(50 * time.Millisecond)
+	}
+	if timesExecuted != 3 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 3)
+	}
+}
+
+func TestDebouncerFlushCancel(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	d := NewDebouncer(func() { timesExecuted++ }, 100*time.Millisecond, Options{Clock: clk})
+
+	d.Call()
+	if !d.Flush() || timesExecuted != 1 {
+		t.Fatalf("flush didn't execute the pending call")
+	}
+	if d.Flush() {
+		t.Error("flush reported a pending call after flushing")
+	}
+
+	d.Call()
+	d.Cancel()
+	clk.Advance(time.Second)
+	if timesExecuted != 1 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 1)
+	}
+}
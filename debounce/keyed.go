@@ -0,0 +1,117 @@
+package debounce
+
+import (
+	"sync"
+	"time"
+)
+
+type keyedentry struct {
+	d *ArgDebouncer
+	// amount of callers which are about to call the debouncer
+	refs int
+}
+
+// KeyedDebouncer debounces calls independently per key, e.g. per file path.
+// A debouncer is created for a key on its first call and removed once its burst ended.
+type KeyedDebouncer struct {
+	mu      sync.Mutex
+	f       func(key string, arg interface{})
+	wait    time.Duration
+	opts    Options
+	entries map[string]*keyedentry
+}
+
+// NewKeyedDebouncer creates a new keyed debouncer which executes the given function per key
+// after calls for that key stopped for the given debounce time.
+func NewKeyedDebouncer(f func(key string, arg interface{}), wait time.Duration, opts Options) *KeyedDebouncer {
+	return &KeyedDebouncer{f: f, wait: wait, opts: opts, entries: make(map[string]*keyedentry)}
+}
+
+// Call schedules an execution of the function for the given key with the given argument.
+func (kd *KeyedDebouncer) Call(key string, arg interface{}) {
+	kd.mu.Lock()
+	e, has := kd.entries[key]
+	if !has {
+		e = &keyedentry{}
+		e.d = NewArgDebouncer(func(arg interface{}) { kd.f(key, arg) }, kd.wait, kd.opts)
+		e.d.onIdle = func() { kd.remove(key, e) }
+		kd.entries[key] = e
+	}
+	e.refs++
+	kd.mu.Unlock()
+
+	e.d.Call(arg)
+
+	kd.mu.Lock()
+	e.refs--
+	kd.mu.Unlock()
+	// the burst might have already ended while we held a reference
+	kd.remove(key, e)
+}
+
+// remove removes the given entry if its burst ended and nobody is about to call it.
+func (kd *KeyedDebouncer) remove(key string, e *keyedentry) {
+	kd.mu.Lock()
+	defer kd.mu.Unlock()
+	if kd.entries[key] == e && e.refs == 0 && e.d.idle() {
+		delete(kd.entries, key)
+	}
+}
+
+func (kd *KeyedDebouncer) get(key string) *ArgDebouncer {
+	kd.mu.Lock()
+	defer kd.mu.Unlock()
+	e, has := kd.entries[key]
+	if !has {
+		return nil
+	}
+	return e.d
+}
+
+func (kd *KeyedDebouncer) all() []*ArgDebouncer {
+	kd.mu.Lock()
+	defer kd.mu.Unlock()
+	all := make([]*ArgDebouncer, 0, len(kd.entries))
+	for _, e := range kd.entries {
+		all = append(all, e.d)
+	}
+	return all
+}
+
+// Flush immediately executes a pending call of the given key.
+// It reports whether a call was pending.
+func (kd *KeyedDebouncer) Flush(key string) bool {
+	d := kd.get(key)
+	if d == nil {
+		return false
+	}
+	return d.Flush()
+}
+
+// Cancel drops a pending call of the given key.
+func (kd *KeyedDebouncer) Cancel(key string) {
+	if d := kd.get(key); d != nil {
+		d.Cancel()
+	}
+}
+
+// FlushAll immediately executes the pending calls of all keys.
+func (kd *KeyedDebouncer) FlushAll() {
+	for _, d := range kd.all() {
+		d.Flush()
+	}
+}
+
+// CancelAll drops the pending calls of all keys.
+func (kd *KeyedDebouncer) CancelAll() {
+	for _, d := range kd.all() {
+		d.Cancel()
+	}
+}
+
+// Len returns the amount of keys with an ongoing burst.
+func (kd *KeyedDebouncer) Len() int {
+	kd.mu.Lock()
+	defer kd.mu.Unlock()
+	return len(kd.entries)
+}
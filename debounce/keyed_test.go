@@ -0,0 +1,64 @@
+package debounce
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArgDebouncer(t *testing.T) {
+	clk := newFakeClock()
+	var last interface{}
+	d := NewArgDebouncer(func(arg interface{}) { last = arg }, 100*time.Millisecond, Options{Clock: clk})
+
+	for x := 0; x < 5; x++ {
+		d.Call(x)
+	}
+	clk.Advance(100 * time.Millisecond)
+	if last != 4 {
+		t.Errorf("executed with %v, expected %v", last, 4)
+	}
+}
+
+func TestKeyedDebouncer(t *testing.T) {
+	clk := newFakeClock()
+	saved := map[string][]interface{}{}
+	kd := NewKeyedDebouncer(func(key string, arg interface{}) {
+		saved[key] = arg.([]interface{})
+	}, 100*time.Millisecond, Options{
+		Coalesce: func(acc interface{}, arg interface{}) interface{} {
+			if acc == nil {
+				return []interface{}{arg}
+			}
+			return append(acc.([]interface{}), arg)
+		},
+		Clock: clk,
+	})
+
+	kd.Call("a.txt", "create")
+	kd.Call("b.txt", "create")
+	clk.Advance(50 * time.Millisecond)
+	kd.Call("a.txt", "write")
+	clk.Advance(50 * time.Millisecond)
+	if _, has := saved["a.txt"]; has {
+		t.Error("a.txt was saved although it was written to again")
+	}
+	if len(saved["b.txt"]) != 1 {
+		t.Errorf("b.txt was saved with %v, expected [create]", saved["b.txt"])
+	}
+	if kd.Len() != 1 {
+		t.Errorf("keys were %d, expected %d", kd.Len(), 1)
+	}
+
+	clk.Advance(50 * time.Millisecond)
+	if len(saved["a.txt"]) != 2 || saved["a.txt"][1] != "write" {
+		t.Errorf("a.txt was saved with %v, expected [create write]", saved["a.txt"])
+	}
+	if kd.Len() != 0 {
+		t.Errorf("keys were %d, expected %d", kd.Len(), 0)
+	}
+
+	kd.Call("c.txt", "create")
+	if !kd.Flush("c.txt") || len(saved["c.txt"]) != 1 {
+		t.Error("flush didn't execute the pending call of c.txt")
+	}
+}
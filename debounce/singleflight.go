@@ -0,0 +1,154 @@
+package debounce
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// ErrPanicked is returned to callers waiting on a call whose function panicked.
+type ErrPanicked struct {
+	Key   string
+	Value interface{}
+}
+
+func (e ErrPanicked) Error() string {
+	return fmt.Sprintf("call %s panicked: %v", e.Key, e.Value)
+}
+
+// ErrGoexit is returned to waiting callers if the executed function called runtime.Goexit.
+var ErrGoexit = errors.New("call exited its goroutine")
+
+// flight is a call which is in flight or completed.
+type flight struct {
+	done chan struct{}
+	val  interface{}
+	err  error
+	// amount of callers which received the result without executing the call
+	dups int
+	// evicts the cached result once it expired
+	expiry clock.Timer
+}
+
+// Group collapses concurrent calls with the same key into a single execution whose result
+// is shared by all callers. Successful results can optionally be cached for a TTL.
+// The zero value is ready to use and doesn't cache results.
+type Group struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+	ttl     time.Duration
+	clock   clock.Clock
+}
+
+// NewGroup creates a new group which caches successful results for the given TTL.
+// If ttl is <= 0, results are not cached.
+func NewGroup(ttl time.Duration) *Group {
+	return &Group{ttl: ttl}
+}
+
+// SetClock sets the clock used to expire cached results.
+func (g *Group) SetClock(clk clock.Clock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.clock = clk
+}
+
+func (g *Group) clk() clock.Clock {
+	if g.clock == nil {
+		return clock.Real
+	}
+	return g.clock
+}
+
+// Do executes the given function unless a call with the same key is already in flight
+// or a cached result exists, in which case that result is returned instead.
+// shared reports whether the result was also handed to other callers or came from the cache.
+// If the function panics, the panic is propagated to the executing caller and
+// waiting callers receive an ErrPanicked. If it calls runtime.Goexit, waiting callers receive ErrGoexit.
+func (g *Group) Do(key string, fn func() (interface{}, error)) (val interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	if f, has := g.flights[key]; has {
+		// completed flights are only kept while their result is cached
+		f.dups++
+		g.mu.Unlock()
+		<-f.done
+		return f.val, f.err, true
+	}
+	f := &flight{done: make(chan struct{})}
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	g.execute(key, f, fn)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return f.val, f.err, f.dups > 0
+}
+
+// execute calls the given function and lands the flight. Panics are propagated after landing.
+func (g *Group) execute(key string, f *flight, fn func() (interface{}, error)) {
+	normalReturn := false
+	var panicked interface{}
+	recovered := false
+	defer func() {
+		switch {
+		case normalReturn:
+			g.land(key, f)
+		case recovered:
+			f.err = ErrPanicked{key, panicked}
+			g.land(key, f)
+			panic(panicked)
+		default:
+			// the deferred calls run because fn called runtime.Goexit, which keeps unwinding
+			f.err = ErrGoexit
+			g.land(key, f)
+		}
+	}()
+
+	func() {
+		defer func() {
+			if !normalReturn {
+				// recover returns nil while unwinding due to runtime.Goexit
+				if panicked = recover(); panicked != nil {
+					recovered = true
+				}
+			}
+		}()
+		f.val, f.err = fn()
+		normalReturn = true
+	}()
+}
+
+// land marks the given flight as completed and keeps it in the cache until the TTL expired if it succeeded.
+func (g *Group) land(key string, f *flight) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.ttl > 0 && f.err == nil {
+		f.expiry = g.clk().AfterFunc(g.ttl, func() {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		})
+	} else if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+	close(f.done)
+}
+
+// Forget drops the cached result of the given key, so that the next call executes again.
+// Callers of a call which is still in flight keep receiving its result.
+func (g *Group) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if f, has := g.flights[key]; has && f.expiry != nil {
+		f.expiry.Stop()
+	}
+	delete(g.flights, key)
+}
@@ -0,0 +1,173 @@
+package debounce
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitForDups blocks until the given amount of callers joined the call of the given key.
+func waitForDups(g *Group, key string, n int) {
+	for {
+		g.mu.Lock()
+		f := g.flights[key]
+		joined := f != nil && f.dups >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestGroup(t *testing.T) {
+	g := &Group{}
+	release := make(chan struct{})
+	var timesExecuted int32
+	fn := func() (interface{}, error) {
+		<-release
+		atomic.AddInt32(&timesExecuted, 1)
+		return "result", nil
+	}
+
+	var wg sync.WaitGroup
+	var sharedResults int32
+	for x := 0; x < 10; x++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err, shared := g.Do("key", fn)
+			if val != "result" || err != nil {
+				t.Errorf("result was %v (%v), expected %v", val, err, "result")
+			}
+			if shared {
+				atomic.AddInt32(&sharedResults, 1)
+			}
+		}()
+	}
+	// the call is released once all other callers joined it
+	waitForDups(g, "key", 9)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&timesExecuted); n != 1 {
+		t.Errorf("result was %d, expected %d", n, 1)
+	}
+	// the executing caller's result was shared as well
+	if n := atomic.LoadInt32(&sharedResults); n != 10 {
+		t.Errorf("shared results were %d, expected %d", n, 10)
+	}
+
+	// without a TTL, completed calls execute again and aren't shared
+	if _, _, shared := g.Do("key", fn); shared {
+		t.Error("result of a single caller was shared")
+	}
+	if n := atomic.LoadInt32(&timesExecuted); n != 2 {
+		t.Errorf("result was %d, expected %d", n, 2)
+	}
+}
+
+func TestGroupGoexit(t *testing.T) {
+	g := &Group{}
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			runtime.Goexit()
+			return nil, nil
+		})
+		t.Error("Do returned although the function exited its goroutine")
+	}()
+
+	<-started
+	errs := make(chan error)
+	go func() {
+		_, err, _ := g.Do("key", func() (interface{}, error) { return nil, nil })
+		errs <- err
+	}()
+	waitForDups(g, "key", 1)
+	close(release)
+	<-done
+	if err := <-errs; err != ErrGoexit {
+		t.Errorf("error was %v, expected %v", err, ErrGoexit)
+	}
+}
+
+func TestGroupCache(t *testing.T) {
+	clk := newFakeClock()
+	g := NewGroup(time.Minute)
+	g.SetClock(clk)
+	timesExecuted := 0
+	fn := func() (interface{}, error) {
+		timesExecuted++
+		return timesExecuted, nil
+	}
+
+	g.Do("key", fn)
+	if val, _, shared := g.Do("key", fn); val != 1 || !shared {
+		t.Errorf("result was %v, expected the cached %v", val, 1)
+	}
+	clk.Advance(time.Minute)
+	if val, _, _ := g.Do("key", fn); val != 2 {
+		t.Errorf("result was %v, expected %v after the cache expired", val, 2)
+	}
+	g.Forget("key")
+	if val, _, _ := g.Do("key", fn); val != 3 {
+		t.Errorf("result was %v, expected %v after forgetting the key", val, 3)
+	}
+
+	// expired results are evicted even if their key isn't requested again
+	for _, key := range []string{"a", "b", "c"} {
+		g.Do(key, fn)
+	}
+	clk.Advance(time.Minute)
+	g.mu.Lock()
+	cached := len(g.flights)
+	g.mu.Unlock()
+	if cached != 0 {
+		t.Errorf("cached results were %d, expected %d", cached, 0)
+	}
+
+	// errors are not cached
+	errFail := errors.New("fail")
+	g.Do("fail", func() (interface{}, error) { return nil, errFail })
+	if _, err, _ := g.Do("fail", fn); err != nil {
+		t.Errorf("error was %v, expected the call to execute again", err)
+	}
+}
+
+func TestGroupPanic(t *testing.T) {
+	g := &Group{}
+	started, release := make(chan struct{}), make(chan struct{})
+	panics := make(chan interface{})
+	go func() {
+		defer func() {
+			panics <- recover()
+		}()
+		g.Do("key", func() (interface{}, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+
+	<-started
+	errs := make(chan error)
+	go func() {
+		_, err, _ := g.Do("key", func() (interface{}, error) { return nil, nil })
+		errs <- err
+	}()
+	waitForDups(g, "key", 1)
+	close(release)
+	if r := <-panics; r != "boom" {
+		t.Errorf("panic was %v, expected %v", r, "boom")
+	}
+	if err, ok := (<-errs).(ErrPanicked); !ok || err.Value != "boom" {
+		t.Errorf("error was %v, expected ErrPanicked", err)
+	}
+}
@@ -0,0 +1,156 @@
+package debounce
+
+import (
+	"sync"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+)
+
+// ThrottleOptions defines the behavior of a Throttler.
+type ThrottleOptions struct {
+	// Edge defines whether the function executes at the start of an interval, at its end or both.
+	// Defaults to Trailing.
+	Edge Edge
+	// Coalesce combines the arguments of all calls which are executed together.
+	// acc is nil for the first call of an interval. By default, the argument of the latest call is used.
+	Coalesce func(acc interface{}, arg interface{}) interface{}
+	// Clock is the clock used to measure time. Defaults to the real clock.
+	Clock clock.Clock
+}
+
+// Throttler executes a function at most once per interval, no matter how often it is called.
+// Calls never block the calling goroutine.
+type Throttler struct {
+	mu       sync.Mutex
+	f        func(arg interface{})
+	interval time.Duration
+	opts     ThrottleOptions
+	// the timer runs while an interval is ongoing
+	timer   clock.Timer
+	pending bool
+	arg     interface{}
+	// invalidates timers which fired while they were stopped
+	gen int
+}
+
+// Throttle creates a new throttler which executes the given function at most once per interval.
+func Throttle(f func(arg interface{}), interval time.Duration, opts ThrottleOptions) *Throttler {
+	if opts.Clock == nil {
+		opts.Clock = clock.Real
+	}
+	return &Throttler{f: f, interval: interval, opts: opts}
+}
+
+// coalesce adds the given argument to the arguments of the pending call. The throttler must be locked.
+func (t *Throttler) coalesce(arg interface{}) {
+	var acc interface{}
+	if t.pending {
+		acc = t.arg
+	}
+	if t.opts.Coalesce != nil {
+		t.arg = t.opts.Coalesce(acc, arg)
+	} else {
+		t.arg = arg
+	}
+	t.pending = true
+}
+
+// take returns the argument of the pending call and clears it. The throttler must be locked.
+func (t *Throttler) take() interface{} {
+	arg := t.arg
+	t.arg = nil
+	t.pending = false
+	return arg
+}
+
+// Call schedules an execution of the function with the given argument.
+// On the leading edge, the function is executed synchronously by the calling goroutine.
+func (t *Throttler) Call(arg interface{}) {
+	t.mu.Lock()
+	if t.timer != nil {
+		// an interval is ongoing
+		if t.opts.Edge.trailing() {
+			t.coalesce(arg)
+		}
+		t.mu.Unlock()
+		return
+	}
+	t.startTimer()
+	if !t.opts.Edge.leading() {
+		t.coalesce(arg)
+		t.mu.Unlock()
+		return
+	}
+	if t.opts.Coalesce != nil {
+		arg = t.opts.Coalesce(nil, arg)
+	}
+	t.mu.Unlock()
+	t.f(arg)
+}
+
+// startTimer starts a new interval. The throttler must be locked.
+func (t *Throttler) startTimer() {
+	t.gen++
+	gen := t.gen
+	t.timer = t.opts.Clock.AfterFunc(t.interval, func() {
+		t.expire(gen)
+	})
+}
+
+// stop ends the current interval. The throttler must be locked.
+func (t *Throttler) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	t.gen++
+}
+
+// expire executes a pending call at the end of an interval, which starts the next interval.
+func (t *Throttler) expire(gen int) {
+	t.mu.Lock()
+	if gen != t.gen {
+		t.mu.Unlock()
+		return
+	}
+	t.timer = nil
+	if !t.pending {
+		t.mu.Unlock()
+		return
+	}
+	arg := t.take()
+	t.startTimer()
+	t.mu.Unlock()
+	t.f(arg)
+}
+
+// Flush immediately executes a pending call and ends the current interval.
+// It reports whether a call was pending.
+func (t *Throttler) Flush() bool {
+	t.mu.Lock()
+	invoke := t.pending
+	arg := t.take()
+	t.stop()
+	t.mu.Unlock()
+
+	if invoke {
+		t.f(arg)
+	}
+	return invoke
+}
+
+// Cancel drops a pending call and ends the current interval.
+func (t *Throttler) Cancel() {
+	t.mu.Lock()
+	t.take()
+	t.stop()
+	t.mu.Unlock()
+}
+
+// Pending reports whether a call is waiting to be executed.
+func (t *Throttler) Pending() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pending
+}
@@ -0,0 +1,70 @@
+package debounce
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottleBoth(t *testing.T) {
+	clk := newFakeClock()
+	args := []interface{}{}
+	th := Throttle(func(arg interface{}) { args = append(args, arg) }, 100*time.Millisecond, ThrottleOptions{Edge: Both, Clock: clk})
+
+	// calls every 10ms for 250ms execute at 0ms, 100ms, 200ms and 300ms
+	for x := 0; x < 25; x++ {
+		th.Call(x)
+		clk.Advance(10 * time.Millisecond)
+	}
+	clk.Advance(time.Second)
+	expected := []interface{}{0, 9, 19, 24}
+	if len(args) != len(expected) {
+		t.Fatalf("executed with %v, expected %v", args, expected)
+	}
+	for i := range args {
+		if args[i] != expected[i] {
+			t.Errorf("executed with %v, expected %v", args, expected)
+		}
+	}
+}
+
+func TestThrottleLeading(t *testing.T) {
+	clk := newFakeClock()
+	timesExecuted := 0
+	th := Throttle(func(interface{}) { timesExecuted++ }, 100*time.Millisecond, ThrottleOptions{Edge: Leading, Clock: clk})
+
+	for x := 0; x < 25; x++ {
+		th.Call(nil)
+		clk.Advance(10 * time.Millisecond)
+	}
+	if timesExecuted != 3 {
+		t.Errorf("result was %d, expected %d", timesExecuted, 3)
+	}
+}
+
+func TestThrottleCoalesce(t *testing.T) {
+	clk := newFakeClock()
+	sums := []int{}
+	th := Throttle(func(arg interface{}) { sums = append(sums, arg.(int)) }, 100*time.Millisecond, ThrottleOptions{
+		Coalesce: func(acc interface{}, arg interface{}) interface{} {
+			if acc == nil {
+				return arg
+			}
+			return acc.(int) + arg.(int)
+		},
+		Clock: clk,
+	})
+
+	for x := 1; x <= 4; x++ {
+		th.Call(x)
+	}
+	if len(sums) != 0 {
+		t.Fatalf("executed before the end of the interval")
+	}
+	clk.Advance(100 * time.Millisecond)
+	th.Call(5)
+	th.Cancel()
+	clk.Advance(time.Second)
+	if len(sums) != 1 || sums[0] != 10 {
+		t.Errorf("executed with %v, expected [10]", sums)
+	}
+}
@@ -0,0 +1,117 @@
+package httplimit
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/luca-moser/belt/concurrent"
+)
+
+// KeyFunc extracts the key by which a request is rate limited.
+type KeyFunc func(r *http.Request) string
+
+// RemoteIP uses the IP address of the client as the key.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Header uses the value of the given request header as the key, e.g. an API key.
+func Header(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// Global returns the same limiter for every key.
+func Global(l concurrent.Limiter) func(key string) concurrent.Limiter {
+	return func(string) concurrent.Limiter {
+		return l
+	}
+}
+
+// PerKey returns the token bucket of the given keyed limiter for each key.
+func PerKey(kl *concurrent.KeyedLimiter) func(key string) concurrent.Limiter {
+	return func(key string) concurrent.Limiter {
+		return kl.Limiter(key)
+	}
+}
+
+// Options defines how requests are rate limited.
+type Options struct {
+	// Key extracts the key of a request. Defaults to RemoteIP.
+	Key KeyFunc
+	// Limiter returns the limiter of the given key.
+	Limiter func(key string) concurrent.Limiter
+	// Wait lets requests wait until they may pass instead of rejecting them.
+	Wait bool
+	// MaxWait rejects requests which would have to wait longer than the given duration.
+	// A zero value lets requests wait until the client goes away.
+	MaxWait time.Duration
+}
+
+// seconds rounds the given duration up to whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// Handler returns a handler which rate limits requests before passing them to the given handler.
+// Limited requests are rejected with 429 Too Many Requests and a Retry-After header.
+// If the limiter implements concurrent.Quota, the X-RateLimit-Limit and X-RateLimit-Remaining
+// headers are set. X-RateLimit-Reset holds the seconds until the next request will be allowed.
+// This function panics if no limiter is given.
+func Handler(next http.Handler, opts Options) http.Handler {
+	if opts.Limiter == nil {
+		panic("no limiter given")
+	}
+	if opts.Key == nil {
+		opts.Key = RemoteIP
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := opts.Limiter(opts.Key(r))
+
+		passed := l.Allow()
+		if !passed && opts.Wait {
+			if opts.MaxWait == 0 || l.Delay() <= opts.MaxWait {
+				ctx := r.Context()
+				if opts.MaxWait > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, opts.MaxWait)
+					defer cancel()
+				}
+				passed = l.Wait(ctx) == nil
+			}
+		}
+
+		delay := l.Delay()
+		if q, ok := l.(concurrent.Quota); ok {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(q.Capacity()))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(q.Remaining()))
+		}
+		w.Header().Set("X-RateLimit-Reset", seconds(delay))
+
+		if !passed {
+			if delay < time.Second {
+				delay = time.Second
+			}
+			w.Header().Set("Retry-After", seconds(delay))
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware returns a function which wraps handlers with Handler using the given options.
+func Middleware(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(next, opts)
+	}
+}
@@ -0,0 +1,92 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luca-moser/belt/clock"
+	"github.com/luca-moser/belt/concurrent"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func request(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerReject(t *testing.T) {
+	kl := concurrent.NewKeyedLimiter(0.5, 2, 0)
+	kl.SetClock(clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)))
+	h := Handler(ok, Options{Limiter: PerKey(kl)})
+
+	rec := request(h, "10.0.0.1:1234")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status was %d, expected %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("limit headers were %s/%s, expected 2/1",
+			rec.Header().Get("X-RateLimit-Limit"), rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	request(h, "10.0.0.1:1234")
+
+	rec = request(h, "10.0.0.1:4321")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status was %d, expected %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("Retry-After was %s, expected %s", rec.Header().Get("Retry-After"), "2")
+	}
+	if rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("X-RateLimit-Remaining was %s, expected %s", rec.Header().Get("X-RateLimit-Remaining"), "0")
+	}
+
+	// other clients are not affected
+	if rec = request(h, "10.0.0.2:1234"); rec.Code != http.StatusOK {
+		t.Errorf("status was %d, expected %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandlerWait(t *testing.T) {
+	clk := clock.NewFake(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+	s := clk.Now()
+	tb := concurrent.NewTokenBucket(50, 1)
+	tb.SetClock(clk)
+	h := Handler(ok, Options{Limiter: Global(tb), Wait: true, MaxWait: time.Second})
+
+	codes := make(chan int, 3)
+	go func() {
+		for i := 0; i < 3; i++ {
+			codes <- request(h, "10.0.0.1:1234").Code
+		}
+	}()
+	// the first request passes right away, the following ones wait for a refill
+	for i := 0; i < 2; i++ {
+		clk.BlockUntil(1)
+		clk.Advance(20 * time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		if code := <-codes; code != http.StatusOK {
+			t.Fatalf("status was %d, expected %d", code, http.StatusOK)
+		}
+	}
+	if elapsed := clk.Now().Sub(s); elapsed != 40*time.Millisecond {
+		t.Errorf("requests took %v, expected them to wait %v", elapsed, 40*time.Millisecond)
+	}
+
+	// requests which would wait longer than MaxWait are rejected right away
+	tb = concurrent.NewTokenBucket(0.1, 1)
+	tb.SetClock(clk)
+	h = Handler(ok, Options{Limiter: Global(tb), Wait: true, MaxWait: 10 * time.Millisecond})
+	request(h, "10.0.0.1:1234")
+	if rec := request(h, "10.0.0.1:1234"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status was %d, expected %d", rec.Code, http.StatusTooManyRequests)
+	}
+}